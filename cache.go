@@ -2,23 +2,50 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
 	"time"
 )
 
 const (
 	cacheFile = "cache.json"
+	// cacheMaxAge is how old the cache can get before it is downloaded again
+	// when filtering without a command.
+	cacheMaxAge = 24 * time.Hour
 )
 
 var (
 	cache videoCache
+
+	// rebuildCache drops all cached videos before a download instead of
+	// merging the fetched videos into them.
+	rebuildCache bool
 )
 
 type videoCache struct {
-	Videos      map[string]videoMeta `json:"videos"`
-	LastUpdated time.Time            `json:"lastUpdated"`
+	Videos           map[string]videoMeta `json:"videos"`
+	LastUpdated      time.Time            `json:"lastUpdated"`
+	YouTubeQuotaUsed int64                `json:"youTubeQuotaUsed"`
+	// FullSyncs is when each source last listed all of its videos.
+	FullSyncs map[string]time.Time `json:"fullSyncs"`
+	// SkippedYouTubeIDs are listed YouTube videos that were left out of the
+	// cache, such as ones in other languages, so incremental listing can
+	// still tell that they are not new.
+	SkippedYouTubeIDs map[string]bool `json:"skippedYouTubeIDs"`
+	// Changes lists the fields of cached videos changed by the last refresh.
+	Changes []videoChange `json:"changes"`
+}
+
+// videoChange is a field of a cached video that a refresh changed.
+type videoChange struct {
+	VideoID string `json:"videoId"`
+	Field   string `json:"field"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
 }
 
 func (c *videoCache) set(video videoMeta) {
@@ -31,20 +58,51 @@ func (c *videoCache) get(id string) (videoMeta, bool) {
 }
 
 func (c *videoCache) load() error {
+	if err := c.read(); err != nil {
+		return err
+	}
+
+	if c.LastUpdated.Add(cacheMaxAge).Before(time.Now()) {
+		log.Println("cache is old, downloading")
+		err := c.download()
+		if errors.Is(err, errNothingFetched) {
+			log.Printf("using the old cache: %v\n", err)
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// open reads the cache file for the commands that only query it, which leave
+// updating it to the update command.
+func (c *videoCache) open() error {
+	if _, err := os.Stat(cacheFile); os.IsNotExist(err) {
+		return fmt.Errorf("no cache file [%v], run disha update to create it", cacheFile)
+	}
+	if err := c.read(); err != nil {
+		return err
+	}
+	if age := time.Since(c.LastUpdated); age > cacheMaxAge {
+		log.Printf("cache was last updated [%v] days ago, run disha update to refresh it\n", int(age.Hours()/24))
+	}
+	return nil
+}
+
+// read loads the cache file as it is, however old it is, replacing what c
+// held before rather than merging into it.
+func (c *videoCache) read() error {
 	data, err := os.ReadFile(cacheFile)
 	if err != nil {
 		return fmt.Errorf("error reading cache file [%v]: %w", cacheFile, err)
 	}
 
-	if err := json.Unmarshal(data, c); err != nil {
+	var read videoCache
+	if err := json.Unmarshal(data, &read); err != nil {
 		return fmt.Errorf("error unmarshalling cache file [%v]: %w", cacheFile, err)
 	}
-
-	if c.LastUpdated.Add(time.Hour * 24).Before(time.Now()) {
-		log.Println("cache is old, downloading")
-		return c.download()
-	}
-
+	*c = read
 	return nil
 }
 
@@ -54,51 +112,219 @@ func (c *videoCache) save() error {
 		return fmt.Errorf("error marshalling cache: %w", err)
 	}
 
-	if err := os.WriteFile(cacheFile, data, 0644); err != nil {
+	if err := writeFileAtomic(cacheFile, data); err != nil {
 		return fmt.Errorf("error writing cache file: %w", err)
 	}
 
 	return nil
 }
 
-func (c *videoCache) download() error {
-	videosFromYouTube, err := getYouTubeContent()
+// writeFileAtomic writes data to a temporary file next to name and renames it
+// to name, so that readers such as disha serve never see a half written file.
+func writeFileAtomic(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
 	if err != nil {
-		return fmt.Errorf("error getting video list from YouTube: %w", err)
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
 	}
-	log.Println("total videos retrieved from YouTube:", len(videosFromYouTube))
-
-	videosFromTT, err := getTTContent()
 	if err != nil {
-		return fmt.Errorf("error getting video list from TT: %w", err)
+		os.Remove(f.Name())
 	}
-	log.Println("total videos retrieved from TT:", len(videosFromTT))
+	return err
+}
 
+func (c *videoCache) download() error {
 	if c.Videos == nil {
 		c.Videos = make(map[string]videoMeta)
-	} else {
+	} else if rebuildCache {
 		clear(c.Videos)
+		clear(c.FullSyncs)
+		clear(c.SkippedYouTubeIDs)
 	}
 
-	for _, video := range append(videosFromTT, videosFromYouTube...) {
-		c.set(video)
+	now := time.Now()
+	c.Changes = nil
+	var failures []fetchFailure
+	merged := 0
+	for _, src := range enabledSources {
+		log.Printf("getting videos from source [%v] (%v)\n", src.name(), src.capabilities())
+
+		inc, ok := src.(incrementalSource)
+		incremental := ok && inc.incremental()
+
+		videos, err := src.fetch()
+		var partial *partialError
+		switch {
+		case errors.As(err, &partial):
+			log.Printf("could only fetch part of [%v], keeping videos it did not list: %v\n", src.name(), err)
+			for _, f := range partial.failures {
+				f.source = src.name()
+				failures = append(failures, f)
+			}
+		case err != nil:
+			log.Printf("error getting video list from [%v], keeping cached videos: %v\n", src.name(), err)
+			failures = append(failures, fetchFailure{source: src.name(), err: err})
+			continue
+		}
+		log.Printf("total videos retrieved from [%v]: %v\n", src.name(), len(videos))
+
+		complete := partial == nil
+		if incremental {
+			log.Printf("[%v] only listed its newest videos, keeping the others\n", src.name())
+			complete = false
+		}
+		if complete {
+			if c.FullSyncs == nil {
+				c.FullSyncs = make(map[string]time.Time)
+			}
+			c.FullSyncs[src.name()] = now
+		}
+
+		added, updated, removed := c.merge(src.name(), videos, now, complete)
+		log.Printf("merged [%v]: %v added, %v updated, %v removed\n", src.name(), added, updated, removed)
+		merged++
 	}
-	c.LastUpdated = time.Now()
+	if merged == 0 {
+		failed := &refreshError{failures: failures}
+		return fmt.Errorf("%w, cache left as it was:\n%v", errNothingFetched, failed.summary())
+	}
+	c.LastUpdated = now
 
 	if err := customizeCache(c); err != nil {
 		return fmt.Errorf("error customizing cache: %w", err)
 	}
+	// Recorded after customizing, which looks up added videos on YouTube.
+	c.YouTubeQuotaUsed = youTubeQuota.total()
 
-	if err := customizeSpotifyCache(c); err != nil {
-		return fmt.Errorf("error customizing cache: %w", err)
+	excluded := excludeVideos(c, overrides.exclude)
+	for rule, count := range excluded {
+		log.Printf("excluded [%v] videos by rule on %v\n", count, rule)
+	}
+
+	talks := groupTalks(c)
+	log.Printf("grouped videos into [%v] talks\n", talks)
+
+	if err := c.save(); err != nil {
+		return err
+	}
+	if _, err := refreshIndex(c.Videos, true); err != nil {
+		log.Printf("error updating search index, searches will rebuild it: %v\n", err)
+	}
+
+	log.Printf("refresh done: [%v] videos cached, [%v] excluded, [%v] changes, [%v] failures\n",
+		len(c.Videos), sumCounts(excluded), len(c.Changes), len(failures))
+	if len(failures) > 0 {
+		return &refreshError{failures: failures}
+	}
+	return nil
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// merge upserts the videos fetched from a source into the cache. When the
+// fetch listed the complete catalogue of the source, cached videos of that
+// source which were not fetched again are kept but marked as removed.
+func (c *videoCache) merge(source string, videos []videoMeta, now time.Time, complete bool) (added, updated, removed int) {
+	seen := make(map[string]bool, len(videos))
+	for _, video := range videos {
+		video.Source = source
+		video.LastSeen = now
+		// Listed again, a video removed before is back, whatever a cached
+		// record handed back by the source says.
+		video.RemovedAt = time.Time{}
+		seen[video.VideoID] = true
+
+		// Cached videos have their patches applied, which are not changes.
+		fetched := video
+		if patch := overrides.patchFor(video.VideoID); patch != nil {
+			patch.applyTo(&fetched)
+		}
+		old, ok := c.get(video.VideoID)
+		if !ok {
+			added++
+		} else if changes := diffVideos(old, fetched); len(changes) > 0 {
+			updated++
+			c.Changes = append(c.Changes, changes...)
+			for _, change := range changes {
+				log.Printf("video [%v] changed [%v]\n", change.VideoID, change.Field)
+			}
+		}
+		c.set(video)
+	}
+
+	if !complete {
+		return added, updated, 0
 	}
 
-	return c.save()
+	for id, video := range c.Videos {
+		if video.Source != source || seen[id] || !video.RemovedAt.IsZero() {
+			continue
+		}
+		video.RemovedAt = now
+		c.Videos[id] = video
+		removed++
+	}
+
+	return added, updated, removed
+}
+
+// bookkeepingFields are the videoMeta fields that say when a video was seen
+// or fetched, or are derived after merging, rather than what it is, so
+// changing them is not a change.
+var bookkeepingFields = map[string]bool{"LastSeen": true, "RemovedAt": true, "MetaFetchedAt": true, "Excluded": true, "TalkID": true}
+
+// diffVideos returns the fields whose content differs between two versions of
+// a video.
+func diffVideos(before, after videoMeta) []videoChange {
+	var changes []videoChange
+	oldValue, newValue := reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := range oldValue.NumField() {
+		field := oldValue.Type().Field(i).Name
+		if bookkeepingFields[field] {
+			continue
+		}
+
+		o, n := oldValue.Field(i).Interface(), newValue.Field(i).Interface()
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		changes = append(changes, videoChange{
+			VideoID: after.VideoID,
+			Field:   field,
+			Old:     fmt.Sprint(o),
+			New:     fmt.Sprint(n),
+		})
+	}
+	return changes
 }
 
 func (c *videoCache) setup(updateCache bool) error {
 	if updateCache {
 		log.Println("update for cache requested!")
+		// The fetched videos are merged into the cached ones, so that
+		// removals and failed sources are noticed rather than dropping videos.
+		if _, err := os.Stat(cacheFile); err == nil {
+			if err := c.read(); err != nil {
+				return err
+			}
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("error checking for cache file: %w", err)
+		}
 		return c.download()
 	}
 
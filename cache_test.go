@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	first := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	c := videoCache{Videos: make(map[string]videoMeta)}
+	added, updated, removed := c.merge("tt", []videoMeta{
+		{VideoID: "a", Name: "A"},
+		{VideoID: "b", Name: "B"},
+	}, first, true)
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 0, updated)
+	assert.Equal(t, 0, removed)
+
+	c.set(videoMeta{VideoID: "y", Name: "Y", Source: "youtube"})
+
+	added, updated, removed = c.merge("tt", []videoMeta{
+		{VideoID: "a", Name: "A renamed"},
+		{VideoID: "c", Name: "C"},
+	}, second, true)
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 1, updated)
+	assert.Equal(t, 1, removed)
+
+	assert.Equal(t, []videoChange{{VideoID: "a", Field: "Name", Old: "A", New: "A renamed"}}, c.Changes)
+
+	v, _ := c.get("a")
+	assert.Equal(t, "A renamed", v.Name)
+	assert.Equal(t, second, v.LastSeen)
+
+	v, ok := c.get("b")
+	assert.True(t, ok)
+	assert.Equal(t, second, v.RemovedAt)
+	assert.Equal(t, first, v.LastSeen)
+
+	v, _ = c.get("y")
+	assert.True(t, v.RemovedAt.IsZero())
+
+	_, _, removed = c.merge("tt", []videoMeta{{VideoID: "a", Name: "A renamed"}}, second, false)
+	assert.Equal(t, 0, removed)
+	v, _ = c.get("c")
+	assert.True(t, v.RemovedAt.IsZero())
+
+	// A removed video listed again, as the cached record youtube hands back,
+	// is no longer removed.
+	b, _ := c.get("b")
+	third := second.Add(24 * time.Hour)
+	added, updated, removed = c.merge("tt", []videoMeta{b, {VideoID: "a", Name: "A renamed"}}, third, true)
+	assert.Equal(t, 0, added)
+	assert.Equal(t, 0, updated)
+	assert.Equal(t, 1, removed, "only c")
+	v, _ = c.get("b")
+	assert.True(t, v.RemovedAt.IsZero())
+	assert.Equal(t, third, v.LastSeen)
+}
+
+// stubSource returns fixed videos, or fails with err.
+type stubSource struct {
+	source string
+	videos []videoMeta
+	err    error
+}
+
+func (s stubSource) name() string                { return s.source }
+func (s stubSource) fetch() ([]videoMeta, error) { return s.videos, s.err }
+func (s stubSource) capabilities() capability    { return capVideo }
+
+func TestSetupMergesIntoSavedCache(t *testing.T) {
+	t.Chdir(t.TempDir())
+	savedSources, savedOverrides := enabledSources, overrides
+	t.Cleanup(func() { enabledSources, overrides = savedSources, savedOverrides })
+	overrides = overrideSet{}
+
+	saved := videoCache{Videos: map[string]videoMeta{
+		"t1": {VideoID: "t1", Name: "Peace", Source: "tt"},
+		"y1": {VideoID: "y1", Name: "Joy", Source: "youtube"},
+	}}
+	assert.NoError(t, saved.save())
+
+	enabledSources = []contentSource{
+		stubSource{source: "tt", videos: []videoMeta{{VideoID: "t2", Name: "Breath", Source: "tt"}}},
+		stubSource{source: "youtube", err: errors.New("quota exceeded")},
+	}
+	var c videoCache
+	var refreshErr *refreshError
+	assert.ErrorAs(t, c.setup(true), &refreshErr)
+
+	assert.Len(t, c.Videos, 3)
+	v, _ := c.get("y1")
+	assert.True(t, v.RemovedAt.IsZero(), "videos of a failed source are kept")
+	v, _ = c.get("t1")
+	assert.False(t, v.RemovedAt.IsZero(), "videos no longer listed are marked removed")
+
+	var reread videoCache
+	assert.NoError(t, reread.read())
+	assert.Len(t, reread.Videos, 3)
+}
+
+func TestSetupWithEverySourceFailing(t *testing.T) {
+	t.Chdir(t.TempDir())
+	savedSources, savedOverrides := enabledSources, overrides
+	t.Cleanup(func() { enabledSources, overrides = savedSources, savedOverrides })
+	overrides = overrideSet{}
+
+	lastUpdated := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	saved := videoCache{LastUpdated: lastUpdated, Videos: map[string]videoMeta{
+		"t1": {VideoID: "t1", Name: "Peace", Source: "tt"},
+	}}
+	assert.NoError(t, saved.save())
+
+	enabledSources = []contentSource{stubSource{source: "tt", err: errors.New("timeout")}}
+	var c videoCache
+	err := c.setup(true)
+	assert.ErrorIs(t, err, errNothingFetched)
+	var refreshErr *refreshError
+	assert.False(t, errors.As(err, &refreshErr), "not a partial refresh")
+
+	var reread videoCache
+	assert.NoError(t, reread.read())
+	assert.Equal(t, lastUpdated, reread.LastUpdated)
+	assert.Len(t, reread.Videos, 1)
+}
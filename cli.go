@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit statuses of the commands, besides exitPartialRefresh.
+const (
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage is returned by a command given invalid flags or arguments, once
+// what is wrong has been printed along with its usage.
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	name string
+	// args follow the flags in the usage, such as [query].
+	args    string
+	summary string
+	run     func(fs *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"search", "[query]", "list the talks matching the filters and search words", runSearch},
+	{"export", "", "write the talks matching the filters to a file or stdout, as json by default", runExport},
+	{"update", "", "fetch talks from every source into the cache", runUpdate},
+	{"stats", "", "sum up the cached talks by source, language and year", runStats},
+	{"validate", "", "check the config and overrides files and the cache without fetching anything", runValidate},
+	{"serve", "", "serve the cache over an HTTP API", runServe},
+}
+
+func printUsage(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "usage: disha <command> [flags]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %v\t%v\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(tw, "\nRun disha help <command> for the flags of a command. Without a command disha\n")
+	fmt.Fprintf(tw, "takes the flags of search and update together, as older versions did.\n")
+	tw.Flush()
+}
+
+// runCommand runs the named command and returns the exit status of disha.
+func runCommand(name string, args []string) int {
+	help := name == "help"
+	if help {
+		if len(args) == 0 {
+			printUsage(os.Stdout)
+			return 0
+		}
+		name, args = args[0], []string{"-h"}
+	}
+
+	i := slices.IndexFunc(commands, func(cmd command) bool { return cmd.name == name })
+	if i < 0 {
+		fmt.Fprintf(os.Stderr, "unknown command [%v]\n\n", name)
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	cmd := commands[i]
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	if help {
+		// Asked for, the usage is the output rather than an error.
+		fs.SetOutput(os.Stdout)
+	}
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "usage: disha %v [flags] %v\n\n%v\n\nflags:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	return exitStatus(cmd.run(fs, args))
+}
+
+func exitStatus(err error) int {
+	var refreshErr *refreshError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.As(err, &refreshErr):
+		log.Printf("%v, kept their last known videos:\n%v\n", refreshErr, refreshErr.summary())
+		return exitPartialRefresh
+	default:
+		log.Println(err)
+		return exitError
+	}
+}
+
+// parseCommand parses the flags of a command, which only takes arguments
+// after them when withArgs is set.
+func parseCommand(fs *flag.FlagSet, args []string, withArgs bool) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if !withArgs && fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments %v\n", fs.Args())
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// settingsFlags are the languages and the config file that every command but
+// stats reads.
+type settingsFlags struct {
+	languages  string
+	configPath string
+}
+
+func (f *settingsFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.languages, "languages", strings.Join(languages, ","), "comma separated languages to fetch and keep on update, replaced by the languages of the config file")
+	fs.StringVar(&f.configPath, "config", "", "yaml config file listing extra youtube channels and playlists to fetch")
+}
+
+func (f *settingsFlags) apply() error {
+	langs, err := parseLanguages(f.languages)
+	if err != nil {
+		return err
+	}
+	languages = langs
+
+	if f.configPath == "" {
+		return nil
+	}
+	cfg, err := loadConfig(f.configPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Languages) > 0 {
+		languages = cfg.Languages
+	}
+	return addYouTubeChannels(cfg.YouTube)
+}
+
+// updateFlags are how an update fetches and corrects the cache.
+type updateFlags struct {
+	rebuild        bool
+	workers        int
+	retries        int64
+	quotaBudget    int64
+	fullSyncEvery  time.Duration
+	revalidateDays int
+	revalidate     bool
+	ytHandles      youTubeFlag
+	ytPlaylists    youTubeFlag
+	overridesPath  string
+	sources        string
+}
+
+func (f *updateFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.rebuild, "rebuild", false, "discard cached videos on update instead of merging fetched ones into them")
+	fs.IntVar(&f.workers, "workers", fetchWorkers, "number of concurrent fetches per stage of an update, such as YouTube handles or TT languages")
+	fs.Int64Var(&f.retries, "retryBudget", defaultRetryBudget, "number of retries shared by all requests of an update")
+	fs.Int64Var(&f.quotaBudget, "quotaBudget", 0, "youtube quota units an update may use before it stops fetching new videos, 0 for no limit")
+	fs.DurationVar(&f.fullSyncEvery, "fullSyncEvery", youTubeFullSyncInterval, "how often an update lists every youtube page instead of stopping at the first page without new videos, 0 for always")
+	fs.IntVar(&f.revalidateDays, "revalidateDays", 0, "look up youtube metadata again for videos fetched more than this many days ago, 0 to never do so")
+	fs.BoolVar(&f.revalidate, "revalidateChanged", revalidateChanged, "look up youtube metadata again for videos whose title, description or thumbnail changed")
+	fs.Var(&f.ytHandles, "ytHandle", "extra youtube handle or channel ID to fetch, optionally with lang= and tag= such as @handle,lang=hi-IN,tag=events (repeatable)")
+	f.ytPlaylists.playlist = true
+	fs.Var(&f.ytPlaylists, "ytPlaylist", "extra youtube playlist ID to fetch, optionally with lang= and tag= (repeatable)")
+	fs.StringVar(&f.overridesPath, "overrides", "", "overrides file applied to the cache after an update, defaults to the embedded data/overrides.yaml")
+	fs.StringVar(&f.sources, "sources", "", "comma separated sources to fetch on update, prefix with - to skip one [tt, youtube, spotify]")
+}
+
+func (f *updateFlags) apply() error {
+	selected, err := selectSources(f.sources)
+	if err != nil {
+		return err
+	}
+	enabledSources = selected
+
+	if err := addYouTubeChannels(f.ytHandles.channels); err != nil {
+		return err
+	}
+	if err := addYouTubeChannels(f.ytPlaylists.channels); err != nil {
+		return err
+	}
+
+	if overrides, err = loadOverrides(f.overridesPath); err != nil {
+		return err
+	}
+
+	rebuildCache = f.rebuild
+	fetchWorkers = f.workers
+	retryBudget.Store(f.retries)
+	youTubeQuota.reset(f.quotaBudget)
+	youTubeFullSyncInterval = f.fullSyncEvery
+	revalidateAfter = time.Duration(f.revalidateDays) * 24 * time.Hour
+	revalidateChanged = f.revalidate
+	return nil
+}
+
+// outputFlags are how the results are written.
+type outputFlags struct {
+	format  string
+	options outputOptions
+	group   bool
+}
+
+func (f *outputFlags) register(fs *flag.FlagSet, format string) {
+	fs.StringVar(&f.format, "format", format, fmt.Sprintf("how to write the results, all but text to stdout %v", outputFormats))
+	fs.StringVar(&f.options.template, "template", "", "go text/template run for every result with -format template, such as '{{.Name}}: {{.ClickURL}}'")
+	fs.StringVar(&f.options.feedTitle, "feedTitle", "Disha", "title of the feed written with -format rss or atom")
+	fs.StringVar(&f.options.feedLink, "feedLink", defaultFeedLink, "link of the feed written with -format rss or atom")
+	fs.BoolVar(&f.group, "group", false, "show one row per talk with the links of every source it is on")
+}
+
+func runSearch(fs *flag.FlagSet, args []string) error {
+	var settings settingsFlags
+	settings.register(fs)
+	var filters filterFlags
+	filters.register(fs)
+	var output outputFlags
+	output.register(fs, "text")
+	if err := parseCommand(fs, args, true); err != nil {
+		return err
+	}
+
+	// Words after the flags are searched along with -q, keeping the ones
+	// quoted as one argument together.
+	words := []string{filters.query}
+	for _, arg := range fs.Args() {
+		if strings.ContainsAny(arg, " \t") && !strings.Contains(arg, `"`) {
+			arg = `"` + arg + `"`
+		}
+		words = append(words, arg)
+	}
+	filters.query = strings.TrimSpace(strings.Join(words, " "))
+
+	return runQuery(settings, filters, output, os.Stdout)
+}
+
+func runExport(fs *flag.FlagSet, args []string) error {
+	var settings settingsFlags
+	settings.register(fs)
+	var filters filterFlags
+	filters.register(fs)
+	var output outputFlags
+	output.register(fs, "json")
+	outPath := fs.String("o", "", "file to write to instead of stdout")
+	if err := parseCommand(fs, args, false); err != nil {
+		return err
+	}
+	if output.format == "" || output.format == "text" {
+		fmt.Fprintf(fs.Output(), "export writes one of %v, use search for text\n", outputFormats[1:])
+		return errUsage
+	}
+
+	if *outPath == "" {
+		return runQuery(settings, filters, output, os.Stdout)
+	}
+	// The file is only replaced once the results are written in full, so a
+	// failed export leaves an earlier one as it was.
+	var b bytes.Buffer
+	if err := runQuery(settings, filters, output, &b); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(*outPath, b.Bytes()); err != nil {
+		return fmt.Errorf("error writing [%v]: %w", *outPath, err)
+	}
+	log.Printf("exported results to [%v]\n", *outPath)
+	return nil
+}
+
+// runQuery writes the cached videos matching the filters to w.
+func runQuery(settings settingsFlags, filters filterFlags, output outputFlags, w io.Writer) error {
+	writeResults, err := newResultWriter(output.format, output.options)
+	if err != nil {
+		return err
+	}
+	if err := settings.apply(); err != nil {
+		return err
+	}
+	params, err := filters.params(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := cache.open(); err != nil {
+		return err
+	}
+	rows, err := findResults(cache.Videos, params, output.group)
+	if err != nil {
+		return err
+	}
+	return writeResults(w, rows)
+}
+
+func runUpdate(fs *flag.FlagSet, args []string) error {
+	var settings settingsFlags
+	settings.register(fs)
+	var update updateFlags
+	update.register(fs)
+	if err := parseCommand(fs, args, false); err != nil {
+		return err
+	}
+	if err := settings.apply(); err != nil {
+		return err
+	}
+	if err := update.apply(); err != nil {
+		return err
+	}
+
+	err := cache.setup(true)
+	var refreshErr *refreshError
+	if err != nil && !errors.As(err, &refreshErr) {
+		return err
+	}
+	log.Printf("updated [%v] with [%v] videos, [%v] changes\n", cacheFile, len(cache.Videos), len(cache.Changes))
+	return err
+}
+
+func runStats(fs *flag.FlagSet, args []string) error {
+	format := fs.String("format", "text", "how to write the stats [text, json]")
+	if err := parseCommand(fs, args, false); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(fs.Output(), "unknown format [%v], expected text or json\n", *format)
+		return errUsage
+	}
+
+	if err := cache.open(); err != nil {
+		return err
+	}
+	stats := computeStats(&cache)
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+	return writeStats(os.Stdout, stats)
+}
+
+func writeStats(w io.Writer, stats cacheStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "videos\t%v\n", stats.Videos)
+	fmt.Fprintf(tw, "talks\t%v\n", stats.Talks)
+	fmt.Fprintf(tw, "removed\t%v\n", stats.Removed)
+	fmt.Fprintf(tw, "excluded\t%v\n", stats.Excluded)
+	fmt.Fprintf(tw, "last updated\t%v\n", stats.LastUpdated.Format(time.DateTime))
+	fmt.Fprintf(tw, "youtube quota used\t%v\n", stats.YouTubeQuotaUsed)
+	for _, counts := range []struct {
+		name   string
+		values map[string]int
+	}{{"source", stats.BySource}, {"language", stats.ByLanguage}, {"year", stats.ByYear}} {
+		fmt.Fprintf(tw, "\nby %v\n", counts.name)
+		keys := make([]string, 0, len(counts.values))
+		for key := range counts.values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			fmt.Fprintf(tw, "  %v\t%v\n", key, counts.values[key])
+		}
+	}
+	return tw.Flush()
+}
+
+// runValidate checks everything an update would read, reporting every
+// problem rather than stopping at the first one.
+func runValidate(fs *flag.FlagSet, args []string) error {
+	var settings settingsFlags
+	settings.register(fs)
+	var update updateFlags
+	update.register(fs)
+	if err := parseCommand(fs, args, false); err != nil {
+		return err
+	}
+
+	var errs []error
+	check := func(what string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", what, err))
+			fmt.Printf("invalid  %v\n", what)
+			return
+		}
+		fmt.Printf("ok       %v\n", what)
+	}
+
+	_, err := parseLanguages(settings.languages)
+	check("languages", err)
+	if settings.configPath != "" {
+		_, err := loadConfig(settings.configPath)
+		check(fmt.Sprintf("config [%v]", settings.configPath), err)
+	}
+	if len(errs) == 0 {
+		err := settings.apply()
+		if err == nil {
+			err = addYouTubeChannels(slices.Concat(update.ytHandles.channels, update.ytPlaylists.channels))
+		}
+		check("youtube channels", err)
+	}
+	_, err = selectSources(update.sources)
+	check("sources", err)
+	overridesName := update.overridesPath
+	if overridesName == "" {
+		overridesName = "embedded data/overrides.yaml"
+	}
+	_, err = loadOverrides(update.overridesPath)
+	check(fmt.Sprintf("overrides [%v]", overridesName), err)
+	if _, err := os.Stat(cacheFile); err == nil {
+		var c videoCache
+		check(fmt.Sprintf("cache [%v]", cacheFile), c.read())
+	} else if !os.IsNotExist(err) {
+		check(fmt.Sprintf("cache [%v]", cacheFile), err)
+	}
+
+	return errors.Join(errs...)
+}
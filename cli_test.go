@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// saveSettings restores the globals that commands set from their flags once
+// the test is done.
+func saveSettings(t *testing.T) {
+	savedLanguages, savedChannels := languages, slices.Clone(youTubeChannels)
+	savedSources, savedOverrides, savedCache := enabledSources, overrides, cache
+	t.Cleanup(func() {
+		languages, youTubeChannels = savedLanguages, savedChannels
+		enabledSources, overrides, cache = savedSources, savedOverrides, savedCache
+	})
+}
+
+func TestCommands(t *testing.T) {
+	saveSettings(t)
+	t.Chdir(t.TempDir())
+	out := filepath.Join(t.TempDir(), "talks.m3u8")
+
+	assert.Equal(t, exitUsage, runCommand("bogus", nil))
+	assert.NoError(t, os.WriteFile(out, []byte("earlier export"), 0644))
+	assert.Equal(t, exitError, runCommand("export", []string{"-o", out}), "no cache yet")
+	data, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "earlier export", string(data), "a failed export keeps the file")
+
+	c := videoCache{LastUpdated: time.Now(), Videos: map[string]videoMeta{
+		"a": {VideoID: "a", Name: "Peace", Language: hindiLang, Source: "tt", ClickURL: "https://tt/a",
+			VideoDuration: 20 * time.Minute, PublishYear: 2025, PublishMonth: time.March, PublishDay: 1},
+		"b": {VideoID: "b", Name: "Joy", Language: englishLang, Source: "youtube", ClickURL: "https://yt/b",
+			VideoDuration: time.Hour, PublishYear: 2024, PublishMonth: time.May, PublishDay: 1},
+	}}
+	assert.NoError(t, c.save())
+
+	assert.Equal(t, 0, runCommand("export", []string{"-format", "m3u8", "-lang", "hi", "-o", out}))
+	data, err = os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "#EXTM3U\n#EXTINF:1200,Peace\nhttps://tt/a\n", string(data))
+
+	assert.Equal(t, exitUsage, runCommand("export", []string{"-format", "text"}))
+	assert.Equal(t, exitUsage, runCommand("stats", []string{"-nope"}))
+	assert.Equal(t, exitUsage, runCommand("update", []string{"extra"}))
+	assert.Equal(t, 0, runCommand("help", []string{"search"}))
+	assert.Equal(t, 0, runCommand("stats", nil))
+	assert.Equal(t, 2, len(cache.Videos))
+
+	// Reading the cache again replaces what was read before.
+	cache.set(videoMeta{VideoID: "stale"})
+	assert.Equal(t, 0, runCommand("stats", nil))
+	assert.Equal(t, 2, len(cache.Videos))
+	assert.Equal(t, exitError, runCommand("validate", []string{"-languages", "hindi"}))
+	assert.Equal(t, exitPartialRefresh, exitStatus(&refreshError{}))
+}
+
+func TestHelpWritesToStdout(t *testing.T) {
+	stdout := filepath.Join(t.TempDir(), "stdout")
+	f, err := os.Create(stdout)
+	assert.NoError(t, err)
+	saved := os.Stdout
+	t.Cleanup(func() { os.Stdout = saved })
+	os.Stdout = f
+
+	assert.Equal(t, 0, runCommand("help", []string{"export"}))
+	assert.NoError(t, f.Close())
+	data, err := os.ReadFile(stdout)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "usage: disha export [flags]")
+	assert.Contains(t, string(data), "-o string")
+}
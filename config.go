@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// youTubeChannel is a YouTube channel, given by handle or ID, or a single
+// playlist that videos are fetched from.
+type youTubeChannel struct {
+	Handle    string `yaml:"handle"`
+	ChannelID string `yaml:"channelId"`
+	Playlist  string `yaml:"playlist"`
+	// Language is used for videos that YouTube knows no supported language for.
+	Language string `yaml:"language"`
+	// Tag is added to the tags of every video fetched from here.
+	Tag string `yaml:"tag"`
+}
+
+func (ch youTubeChannel) String() string {
+	switch {
+	case ch.Handle != "":
+		return ch.Handle
+	case ch.ChannelID != "":
+		return ch.ChannelID
+	default:
+		return "playlist " + ch.Playlist
+	}
+}
+
+func (ch youTubeChannel) validate() error {
+	set := 0
+	for _, v := range []string{ch.Handle, ch.ChannelID, ch.Playlist} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("youtube entry [%+v] needs exactly one of handle, channelId or playlist", ch)
+	}
+	if ch.Handle != "" && !strings.HasPrefix(ch.Handle, "@") {
+		return fmt.Errorf("youtube handle [%v] should start with @", ch.Handle)
+	}
+	if ch.Language != "" {
+		if err := checkLanguageCode(ch.Language); err != nil {
+			return fmt.Errorf("youtube entry [%v]: %w", ch, err)
+		}
+	}
+	return nil
+}
+
+// sameAs reports whether both fetch the same channel or playlist.
+func (ch youTubeChannel) sameAs(other youTubeChannel) bool {
+	return ch.Handle != "" && strings.EqualFold(ch.Handle, other.Handle) ||
+		ch.ChannelID != "" && ch.ChannelID == other.ChannelID ||
+		ch.Playlist != "" && ch.Playlist == other.Playlist
+}
+
+// addYouTubeChannels adds channels configured at runtime to youTubeChannels,
+// once languages are set. A channel fetched already, such as a built-in
+// handle, only gets the language and tag it had none of. A language that is
+// not one of languages is rejected, as every video given it would be dropped.
+func addYouTubeChannels(channels []youTubeChannel) error {
+	for _, ch := range channels {
+		if ch.Language != "" && !supportedLang(ch.Language) {
+			return fmt.Errorf("youtube entry [%v]: language [%v] is not one of %v", ch, ch.Language, languages)
+		}
+
+		i := slices.IndexFunc(youTubeChannels, ch.sameAs)
+		if i < 0 {
+			youTubeChannels = append(youTubeChannels, ch)
+			continue
+		}
+		log.Printf("youtube entry [%v] is fetched already, fetching it once\n", ch)
+		youTubeChannels[i].Language = cmp.Or(youTubeChannels[i].Language, ch.Language)
+		youTubeChannels[i].Tag = cmp.Or(youTubeChannels[i].Tag, ch.Tag)
+	}
+	return nil
+}
+
+// config is the file given with -config, for settings that change more often
+// than releases do.
+type config struct {
+	// Languages replaces the default languages when set.
+	Languages []string `yaml:"languages"`
+	// YouTube lists channels and playlists fetched on top of allYtHandles.
+	YouTube []youTubeChannel `yaml:"youtube"`
+}
+
+func loadConfig(path string) (config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config{}, fmt.Errorf("error reading config file [%v]: %w", path, err)
+	}
+
+	var cfg config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return config{}, fmt.Errorf("error decoding config file [%v]: %w", path, err)
+	}
+
+	for _, lang := range cfg.Languages {
+		if err := checkLanguageCode(lang); err != nil {
+			return config{}, fmt.Errorf("error in config file [%v]: %w", path, err)
+		}
+	}
+	for _, ch := range cfg.YouTube {
+		if err := ch.validate(); err != nil {
+			return config{}, fmt.Errorf("error in config file [%v]: %w", path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// youTubeFlag collects repeated -ytHandle or -ytPlaylist flags. Each value is
+// a handle, channel ID or playlist ID optionally followed by lang= and tag=,
+// such as "@wopgyt,lang=hi-IN,tag=events".
+type youTubeFlag struct {
+	playlist bool
+	channels []youTubeChannel
+}
+
+func (f *youTubeFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	names := make([]string, 0, len(f.channels))
+	for _, ch := range f.channels {
+		names = append(names, ch.String())
+	}
+	return strings.Join(names, " ")
+}
+
+func (f *youTubeFlag) Set(value string) error {
+	parts := strings.Split(value, ",")
+
+	var ch youTubeChannel
+	switch id := strings.TrimSpace(parts[0]); {
+	case f.playlist:
+		ch.Playlist = id
+	case strings.HasPrefix(id, "@"):
+		ch.Handle = id
+	default:
+		ch.ChannelID = id
+	}
+
+	for _, option := range parts[1:] {
+		key, val, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch key {
+		case "lang":
+			ch.Language = val
+		case "tag":
+			ch.Tag = val
+		default:
+			return fmt.Errorf("unknown option [%v], expected lang= or tag=", key)
+		}
+	}
+
+	if err := ch.validate(); err != nil {
+		return err
+	}
+	f.channels = append(f.channels, ch)
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYouTubeFlag(t *testing.T) {
+	handles := &youTubeFlag{}
+	assert.NoError(t, handles.Set("@wopgyt,lang=hi-IN,tag=events"))
+	assert.NoError(t, handles.Set("UCxyz"))
+	assert.Equal(t, []youTubeChannel{
+		{Handle: "@wopgyt", Language: hindiLang, Tag: "events"},
+		{ChannelID: "UCxyz"},
+	}, handles.channels)
+
+	playlists := &youTubeFlag{playlist: true}
+	assert.NoError(t, playlists.Set("PLabc,tag=retreat"))
+	assert.Equal(t, []youTubeChannel{{Playlist: "PLabc", Tag: "retreat"}}, playlists.channels)
+
+	assert.EqualError(t, handles.Set("@x,colour=red"), "unknown option [colour], expected lang= or tag=")
+	assert.EqualError(t, handles.Set("@x,lang=hindi"), "youtube entry [@x]: invalid language [hindi], expected such as hi-IN or es-ES")
+}
+
+func TestAddYouTubeChannels(t *testing.T) {
+	savedChannels, savedLanguages := youTubeChannels, languages
+	t.Cleanup(func() { youTubeChannels, languages = savedChannels, savedLanguages })
+	youTubeChannels = handleChannels([]string{wopgHandle})
+	languages = []string{hindiLang, englishLang}
+
+	assert.NoError(t, addYouTubeChannels([]youTubeChannel{
+		{Handle: "@WOPGYT", Tag: "events"},
+		{Playlist: "PLabc"},
+		{Playlist: "PLabc", Language: hindiLang},
+	}))
+	assert.Equal(t, []youTubeChannel{
+		{Handle: wopgHandle, Tag: "events"},
+		{Playlist: "PLabc", Language: hindiLang},
+	}, youTubeChannels)
+
+	assert.EqualError(t, addYouTubeChannels([]youTubeChannel{{Handle: "@x", Language: "mr-IN"}}),
+		"youtube entry [@x]: language [mr-IN] is not one of [hi-IN en-US]")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateFlags are the date filters as given on the command line.
+type dateFlags struct {
+	from  string
+	to    string
+	month string
+	since string
+	this  string
+}
+
+// dateRange returns the first and last publish day the date filters allow,
+// either zero when unbounded. Filters given together must all hold. Days are
+// midnight UTC, as publishDate returns them, and relative filters count from
+// the local day of now.
+func (f dateFlags) dateRange(now time.Time) (from, to time.Time, err error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	narrow := func(first, last time.Time) {
+		if !first.IsZero() && (from.IsZero() || first.After(from)) {
+			from = first
+		}
+		if !last.IsZero() && (to.IsZero() || last.Before(to)) {
+			to = last
+		}
+	}
+
+	if f.from != "" {
+		first, err := time.Parse(time.DateOnly, f.from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -from date [%v], expected such as 2025-11-21", f.from)
+		}
+		narrow(first, time.Time{})
+	}
+	if f.to != "" {
+		last, err := time.Parse(time.DateOnly, f.to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -to date [%v], expected such as 2025-11-21", f.to)
+		}
+		narrow(time.Time{}, last)
+	}
+	if f.month != "" {
+		first, err := time.Parse("2006-01", f.month)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -month [%v], expected such as 2025-11", f.month)
+		}
+		narrow(first, first.AddDate(0, 1, -1))
+	}
+	if f.since != "" {
+		first, err := parseSince(f.since, today)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		narrow(first, time.Time{})
+	}
+	if f.this != "" {
+		var first time.Time
+		switch strings.ToLower(f.this) {
+		case "week":
+			first = mostRecentWeekday(today, time.Monday)
+		case "month":
+			first = today.AddDate(0, 0, 1-today.Day())
+		case "year":
+			first = time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		default:
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -this [%v], expected week, month or year", f.this)
+		}
+		narrow(first, today)
+	}
+
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("date filters leave no day between [%v] and [%v]",
+			from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	return from, to, nil
+}
+
+// parseSince parses the start of a -since window: a number of days, weeks,
+// months or years back such as 30d or 2w, a weekday for the most recent one,
+// today when it is that day, or a date.
+func parseSince(since string, today time.Time) (time.Time, error) {
+	if wd, ok := parseWeekday(since); ok {
+		return mostRecentWeekday(today, wd), nil
+	}
+	if day, err := time.Parse(time.DateOnly, since); err == nil {
+		return day, nil
+	}
+
+	if len(since) > 1 {
+		n, err := strconv.Atoi(since[:len(since)-1])
+		if err == nil && n >= 0 {
+			switch since[len(since)-1] {
+			case 'd':
+				return today.AddDate(0, 0, -n), nil
+			case 'w':
+				return today.AddDate(0, 0, -7*n), nil
+			case 'm':
+				return today.AddDate(0, -n, 0), nil
+			case 'y':
+				return today.AddDate(-n, 0, 0), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid -since [%v], expected such as 30d, 2w, 6m, 1y, sunday or 2025-11-21", since)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateRange(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2025, time.November, 19, 21, 30, 0, 0, time.Local)
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC) }
+
+	for _, tc := range []struct {
+		flags    dateFlags
+		from, to time.Time
+	}{
+		{dateFlags{}, time.Time{}, time.Time{}},
+		{dateFlags{from: "2025-11-01", to: "2025-11-10"}, day(time.November, 1), day(time.November, 10)},
+		{dateFlags{month: "2025-02"}, day(time.February, 1), day(time.February, 28)},
+		{dateFlags{since: "30d"}, day(time.October, 20), time.Time{}},
+		{dateFlags{since: "2w"}, day(time.November, 5), time.Time{}},
+		{dateFlags{since: "sunday"}, day(time.November, 16), time.Time{}},
+		{dateFlags{since: "Wednesday"}, day(time.November, 19), time.Time{}},
+		{dateFlags{this: "week"}, day(time.November, 17), day(time.November, 19)},
+		{dateFlags{this: "month"}, day(time.November, 1), day(time.November, 19)},
+		{dateFlags{this: "year", to: "2025-03-31"}, day(time.January, 1), day(time.March, 31)},
+		{dateFlags{month: "2025-11", since: "1w"}, day(time.November, 12), day(time.November, 30)},
+	} {
+		from, to, err := tc.flags.dateRange(now)
+		assert.NoError(t, err, "%+v", tc.flags)
+		assert.Equal(t, tc.from, from, "%+v", tc.flags)
+		assert.Equal(t, tc.to, to, "%+v", tc.flags)
+	}
+
+	_, _, err := dateFlags{since: "soon"}.dateRange(now)
+	assert.EqualError(t, err, "invalid -since [soon], expected such as 30d, 2w, 6m, 1y, sunday or 2025-11-21")
+	_, _, err = dateFlags{this: "decade"}.dateRange(now)
+	assert.EqualError(t, err, "invalid -this [decade], expected week, month or year")
+	_, _, err = dateFlags{from: "2025-12-01", month: "2025-11"}.dateRange(now)
+	assert.EqualError(t, err, "date filters leave no day between [2025-12-01] and [2025-11-30]")
+}
+
+func TestSortVideosByPublishDay(t *testing.T) {
+	videos := sortVideosByPublishYear([]videoMeta{
+		{VideoID: "early", PublishYear: 2025, PublishMonth: time.November, PublishDay: 2},
+		{VideoID: "older", PublishYear: 2025, PublishMonth: time.October, PublishDay: 30},
+		{VideoID: "late", PublishYear: 2025, PublishMonth: time.November, PublishDay: 21},
+	})
+	var ids []string
+	for _, v := range videos {
+		ids = append(ids, v.VideoID)
+	}
+	assert.Equal(t, []string{"late", "early", "older"}, ids)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// exclusionRule leaves matching videos out of the results, such as trailers,
+// promos or re-uploads. Every condition the rule has must match.
+type exclusionRule struct {
+	ID          string `yaml:"id"`
+	Title       string `yaml:"title"`
+	Channel     string `yaml:"channel"`
+	ShorterThan string `yaml:"shorterThan"`
+	Reason      string `yaml:"reason"`
+
+	line        int
+	title       *regexp.Regexp
+	shorterThan time.Duration
+}
+
+var exclusionFields = []string{"id", "title", "channel", "shorterThan", "reason"}
+
+func decodeExclusion(item *yaml.Node, fail func(int, string, ...any)) (exclusionRule, bool) {
+	if item.Kind != yaml.MappingNode {
+		fail(item.Line, "entry should be a mapping of fields")
+		return exclusionRule{}, false
+	}
+
+	ok := true
+	for i := 0; i < len(item.Content); i += 2 {
+		if field := item.Content[i]; !slices.Contains(exclusionFields, field.Value) {
+			fail(field.Line, "unknown field [%v], expected one of %v", field.Value, exclusionFields)
+			ok = false
+		}
+	}
+	if !ok {
+		return exclusionRule{}, false
+	}
+
+	var rule exclusionRule
+	if err := item.Decode(&rule); err != nil {
+		fail(item.Line, "%v", err)
+		return exclusionRule{}, false
+	}
+	rule.line = item.Line
+
+	if rule.ID == "" && rule.Title == "" && rule.Channel == "" && rule.ShorterThan == "" {
+		fail(rule.line, "exclusion needs at least one of [id], [title], [channel] or [shorterThan]")
+		return exclusionRule{}, false
+	}
+	if rule.Title != "" {
+		title, err := regexp.Compile(rule.Title)
+		if err != nil {
+			fail(rule.line, "invalid title pattern [%v]: %v", rule.Title, err)
+			ok = false
+		}
+		rule.title = title
+	}
+	if rule.ShorterThan != "" {
+		shorterThan, err := time.ParseDuration(rule.ShorterThan)
+		if err != nil || shorterThan <= 0 {
+			fail(rule.line, "invalid shorterThan [%v], expected such as 90s", rule.ShorterThan)
+			ok = false
+		}
+		rule.shorterThan = shorterThan
+	}
+
+	return rule, ok
+}
+
+func (r *exclusionRule) matches(video videoMeta) bool {
+	if r.ID != "" && video.VideoID != r.ID {
+		return false
+	}
+	if r.title != nil && !r.title.MatchString(video.Name) {
+		return false
+	}
+	if r.Channel != "" && !strings.EqualFold(video.Channel, r.Channel) {
+		return false
+	}
+	// A duration of 0 is unknown rather than short.
+	if r.shorterThan > 0 && (video.VideoDuration <= 0 || video.VideoDuration >= r.shorterThan) {
+		return false
+	}
+	return true
+}
+
+func (r *exclusionRule) String() string {
+	var conditions []string
+	if r.ID != "" {
+		conditions = append(conditions, "id "+r.ID)
+	}
+	if r.Title != "" {
+		conditions = append(conditions, "title "+r.Title)
+	}
+	if r.Channel != "" {
+		conditions = append(conditions, "channel "+r.Channel)
+	}
+	if r.ShorterThan != "" {
+		conditions = append(conditions, "shorter than "+r.ShorterThan)
+	}
+
+	desc := strings.Join(conditions, ", ")
+	if r.Reason != "" {
+		desc += " (" + r.Reason + ")"
+	}
+	return fmt.Sprintf("line %v: %v", r.line, desc)
+}
+
+// excludeVideos marks the cached videos matching a rule as excluded, logs
+// each of them and returns how many each rule excluded.
+func excludeVideos(c *videoCache, rules []exclusionRule) map[string]int {
+	counts := make(map[string]int)
+	for id, video := range c.Videos {
+		video.Excluded = ""
+		for _, rule := range rules {
+			if rule.matches(video) {
+				video.Excluded = rule.String()
+				counts[video.Excluded]++
+				log.Printf("excluded video [%v] %q by rule on %v\n", id, video.Name, video.Excluded)
+				break
+			}
+		}
+		c.Videos[id] = video
+	}
+	return counts
+}
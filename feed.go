@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+const defaultFeedLink = "https://github.com/tech-for-peace/disha"
+
+type rssFeed struct {
+	XMLName  xml.Name   `xml:"rss"`
+	Version  string     `xml:"version,attr"`
+	ITunesNS string     `xml:"xmlns:itunes,attr"`
+	Channel  rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title          string     `xml:"title"`
+	Link           string     `xml:"link"`
+	Description    string     `xml:"description"`
+	Language       string     `xml:"language,omitempty"`
+	LastBuildDate  string     `xml:"lastBuildDate"`
+	Generator      string     `xml:"generator"`
+	ITunesAuthor   string     `xml:"itunes:author"`
+	ITunesExplicit string     `xml:"itunes:explicit"`
+	ITunesCategory rssITunes  `xml:"itunes:category"`
+	ITunesImage    *rssITunes `xml:"itunes:image"`
+	Items          []rssItem  `xml:"item"`
+}
+
+// rssITunes is an iTunes element whose value is an attribute, such as the
+// category text or the image href.
+type rssITunes struct {
+	Text string `xml:"text,attr,omitempty"`
+	Href string `xml:"href,attr,omitempty"`
+}
+
+type rssItem struct {
+	Title          string     `xml:"title"`
+	Link           string     `xml:"link"`
+	GUID           rssGUID    `xml:"guid"`
+	Description    string     `xml:"description,omitempty"`
+	PubDate        string     `xml:"pubDate"`
+	ITunesDuration string     `xml:"itunes:duration,omitempty"`
+	ITunesSummary  string     `xml:"itunes:summary,omitempty"`
+	ITunesImage    *rssITunes `xml:"itunes:image"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// writeRSS writes an RSS 2.0 feed with iTunes tags. Items link to the page of
+// each talk rather than enclosing a media file, as no source offers one, so
+// podcast apps that only play enclosures show no episodes.
+func writeRSS(opts outputOptions) resultWriter {
+	return func(w io.Writer, rows []resultRow) error {
+		channel := rssChannel{
+			Title:          opts.feedTitle,
+			Link:           opts.feedLink,
+			Description:    opts.feedTitle,
+			Language:       feedLanguage(rows),
+			LastBuildDate:  feedUpdated(rows).Format(time.RFC1123Z),
+			Generator:      "disha",
+			ITunesAuthor:   opts.feedTitle,
+			ITunesExplicit: "false",
+			ITunesCategory: rssITunes{Text: "Religion & Spirituality"},
+			Items:          []rssItem{},
+		}
+		for _, row := range rows {
+			item := rssItem{
+				Title:         row.Name,
+				Link:          row.ClickURL,
+				GUID:          rssGUID{Value: row.Source + ":" + row.VideoID},
+				Description:   row.Description,
+				PubDate:       publishDate(row.video).Format(time.RFC1123Z),
+				ITunesSummary: row.Description,
+			}
+			if row.video.VideoDuration > 0 {
+				item.ITunesDuration = itunesDuration(row.video.VideoDuration)
+			}
+			if row.ThumbnailURL != "" {
+				item.ITunesImage = &rssITunes{Href: row.ThumbnailURL}
+			}
+			channel.Items = append(channel.Items, item)
+		}
+		if len(rows) > 0 && rows[0].ThumbnailURL != "" {
+			channel.ITunesImage = &rssITunes{Href: rows[0].ThumbnailURL}
+		}
+
+		feed := rssFeed{
+			Version:  "2.0",
+			ITunesNS: "http://www.itunes.com/dtds/podcast-1.0.dtd",
+			Channel:  channel,
+		}
+		return writeXML(w, "rss", feed)
+	}
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Updated   string   `xml:"updated"`
+	Published string   `xml:"published"`
+	Link      atomLink `xml:"link"`
+	Summary   string   `xml:"summary,omitempty"`
+}
+
+// writeAtom writes an Atom feed. Entries are identified by their link, which
+// stays the same for as long as a talk is listed.
+func writeAtom(opts outputOptions) resultWriter {
+	return func(w io.Writer, rows []resultRow) error {
+		feed := atomFeed{
+			ID:      opts.feedLink,
+			Title:   opts.feedTitle,
+			Updated: feedUpdated(rows).Format(time.RFC3339),
+			Link:    atomLink{Href: opts.feedLink},
+			Author:  atomAuthor{Name: opts.feedTitle},
+			Entries: []atomEntry{},
+		}
+		for _, row := range rows {
+			published := publishDate(row.video).Format(time.RFC3339)
+			feed.Entries = append(feed.Entries, atomEntry{
+				ID:        row.ClickURL,
+				Title:     row.Name,
+				Updated:   published,
+				Published: published,
+				Link:      atomLink{Href: row.ClickURL, Rel: "alternate"},
+				Summary:   row.Description,
+			})
+		}
+		return writeXML(w, "atom", feed)
+	}
+}
+
+func writeXML(w io.Writer, format string, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("error writing %v: %w", format, err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("error writing %v: %w", format, err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// feedUpdated is when the newest talk of a feed was published, or the start
+// of time for an empty feed, so that the same results give the same feed.
+func feedUpdated(rows []resultRow) time.Time {
+	var updated time.Time
+	for _, row := range rows {
+		if published := publishDate(row.video); published.After(updated) {
+			updated = published
+		}
+	}
+	if updated.IsZero() {
+		return time.Unix(0, 0).UTC()
+	}
+	return updated
+}
+
+// feedLanguage is the language of a feed whose talks are all in one.
+func feedLanguage(rows []resultRow) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	for _, row := range rows[1:] {
+		if row.Language != rows[0].Language {
+			return ""
+		}
+	}
+	return rows[0].Language
+}
+
+func itunesDuration(d time.Duration) string {
+	seconds := int(d.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
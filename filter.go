@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// filterFlags are the filters as flags, shared by the command line and the
+// query parameters of the server.
+type filterFlags struct {
+	query       string
+	lang        string
+	audioLang   string
+	subLang     string
+	durationMin time.Duration
+	durationMax time.Duration
+	publishYear int
+	audioOnly   bool
+	source      string
+	dates       dateFlags
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.query, "q", "", `search title and description, such as title:"inner peace" (breath OR श्वास) -trailer`)
+	fs.StringVar(&f.lang, "lang", "", "filter by language [such as en-US, hi-IN, or en for en-US]")
+	fs.StringVar(&f.audioLang, "audioLang", "", "filter by a language the talk can be heard in, original or dubbed [such as hi-IN]")
+	fs.StringVar(&f.subLang, "subLang", "", "filter by subtitle language, with -audioLang lists talks matching either [such as hi-IN]")
+	fs.DurationVar(&f.durationMin, "minDuration", 0, "filter by minimum duration [such as 30s, 20m, 1h]")
+	fs.DurationVar(&f.durationMax, "maxDuration", 0, "filter by maximum duration [such as 30s, 20m, 1h]")
+	fs.IntVar(&f.publishYear, "publishYear", 0, "filter by publish year [such as 2022, 2023, 2024]")
+	fs.BoolVar(&f.audioOnly, "audioOnly", false, "filter by audio only talks")
+	fs.StringVar(&f.dates.from, "from", "", "filter by first publish day [such as 2025-11-21]")
+	fs.StringVar(&f.dates.to, "to", "", "filter by last publish day [such as 2025-11-30]")
+	fs.StringVar(&f.dates.month, "month", "", "filter by publish month [such as 2025-11]")
+	fs.StringVar(&f.dates.since, "since", "", "filter by published since [such as 30d, 2w, 6m, 1y, sunday or 2025-11-21]")
+	fs.StringVar(&f.dates.this, "this", "", "filter by published this [week, month or year]")
+	fs.StringVar(&f.source, "source", "", "filter by source [youtube, tt]")
+}
+
+// params returns the filters to apply, with relative dates counted from now.
+func (f *filterFlags) params(now time.Time) (filterParam, error) {
+	from, to, err := f.dates.dateRange(now)
+	if err != nil {
+		return filterParam{}, err
+	}
+
+	source := f.source
+	if source == "tt" {
+		source = "timelesstoday"
+	}
+	return filterParam{
+		query:       f.query,
+		lang:        normalizeLang(f.lang),
+		audioLang:   normalizeLang(f.audioLang),
+		subLang:     normalizeLang(f.subLang),
+		durationMin: f.durationMin,
+		durationMax: f.durationMax,
+		publishYear: f.publishYear,
+		audioOnly:   f.audioOnly,
+		from:        from,
+		to:          to,
+		source:      source,
+	}, nil
+}
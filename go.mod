@@ -5,6 +5,7 @@ go 1.25
 require (
 	github.com/PuerkitoBio/goquery v1.11.0
 	github.com/stretchr/testify v1.11.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -12,5 +13,4 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/net v0.47.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
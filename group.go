@@ -0,0 +1,250 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// talkTitleSimilarity is the share of title words two videos need in
+	// common to be taken as the same talk.
+	talkTitleSimilarity = 0.8
+	// talkDurationTolerance is how much the durations of the same talk may
+	// differ, as a share of the longer one, but never less than
+	// talkMinDurationTolerance to allow for intros cut on some sources.
+	talkDurationTolerance    = 0.05
+	talkMinDurationTolerance = 90 * time.Second
+	// talkPublishWindow is how far apart the same talk may be published on
+	// different sources.
+	talkPublishWindow = 60 * 24 * time.Hour
+)
+
+// undatedSources are the sources whose publish dates are made up, such as
+// Spotify whose episodes only show a weekday, so their videos are grouped
+// with talks published at any time.
+var undatedSources = map[string]bool{"spotify": true}
+
+// talkSourceRank orders the sources a talk's canonical video is picked from.
+var talkSourceRank = []string{"tt", "youtube", "override", "spotify"}
+
+// titleNoise are title words that say where or how a talk is published
+// rather than what it is.
+var titleNoise = map[string]bool{"official": true, "video": true, "audio": true, "hd": true, "full": true, "podcast": true}
+
+type talkCandidate struct {
+	id        string
+	words     map[string]bool
+	published time.Time
+	duration  time.Duration
+	undated   bool
+}
+
+// groupTalks clusters the videos that are the same talk published on several
+// sources, or several times on one, and sets their TalkID to the VideoID of
+// the canonical video of the talk. Removed and excluded videos are left out of
+// every talk. It returns the number of talks.
+func groupTalks(c *videoCache) int {
+	var candidates []talkCandidate
+	for id, video := range c.Videos {
+		if !video.RemovedAt.IsZero() || video.Excluded != "" {
+			video.TalkID = ""
+			c.Videos[id] = video
+			continue
+		}
+		candidates = append(candidates, talkCandidate{
+			id:        id,
+			words:     titleWords(video.Name),
+			published: publishDate(video),
+			duration:  video.VideoDuration,
+			undated:   undatedSources[video.Source],
+		})
+	}
+	slices.SortFunc(candidates, func(a, b talkCandidate) int {
+		if n := a.published.Compare(b.published); n != 0 {
+			return n
+		}
+		return strings.Compare(a.id, b.id)
+	})
+
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Candidates are sorted by publish date, so only those published within
+	// the window after each one need comparing with it. Undated ones join the
+	// talk they match best instead.
+	for i := range candidates {
+		for j := i + 1; j < len(candidates); j++ {
+			if candidates[i].undated || candidates[j].undated {
+				continue
+			}
+			if candidates[j].published.Sub(candidates[i].published) > talkPublishWindow {
+				break
+			}
+			if sameTalk(candidates[i], candidates[j]) {
+				parent[root(j)] = root(i)
+			}
+		}
+	}
+	for i := range candidates {
+		if candidates[i].undated {
+			if j := closestTalk(candidates, i); j >= 0 {
+				parent[root(i)] = root(j)
+			}
+		}
+	}
+
+	talks := make(map[int][]string)
+	for i, candidate := range candidates {
+		r := root(i)
+		talks[r] = append(talks[r], candidate.id)
+	}
+	for _, ids := range talks {
+		talkID := slices.MinFunc(ids, func(a, b string) int {
+			return compareCanonical(c.Videos[a], c.Videos[b])
+		})
+		for _, id := range ids {
+			video := c.Videos[id]
+			video.TalkID = talkID
+			c.Videos[id] = video
+		}
+	}
+	return len(talks)
+}
+
+// closestTalk returns the dated candidate that the undated candidate i is the
+// same talk as, or -1. Joining only one keeps an undated video from bridging
+// talks of the same title published years apart, so the one with the closest
+// duration is picked, then the one published closest to the made up date.
+func closestTalk(candidates []talkCandidate, i int) int {
+	best := -1
+	for j, candidate := range candidates {
+		if candidate.undated || !sameTalk(candidates[i], candidate) {
+			continue
+		}
+		if best < 0 || closer(candidates[i], candidate, candidates[best]) {
+			best = j
+		}
+	}
+	return best
+}
+
+// closer reports whether a is a closer match for the undated candidate than b.
+func closer(undated, a, b talkCandidate) bool {
+	da, db := (a.duration - undated.duration).Abs(), (b.duration - undated.duration).Abs()
+	if da != db {
+		return da < db
+	}
+	return a.published.Sub(undated.published).Abs() < b.published.Sub(undated.published).Abs()
+}
+
+func sameTalk(a, b talkCandidate) bool {
+	if titleSimilarity(a.words, b.words) < talkTitleSimilarity {
+		return false
+	}
+	if a.duration == 0 || b.duration == 0 {
+		return true
+	}
+	diff := max(a.duration, b.duration) - min(a.duration, b.duration)
+	tolerance := max(talkMinDurationTolerance, time.Duration(float64(max(a.duration, b.duration))*talkDurationTolerance))
+	return diff <= tolerance
+}
+
+// compareCanonical orders videos of the same talk by how well they stand for
+// it: by source, then oldest first.
+func compareCanonical(a, b videoMeta) int {
+	if n := sourceRank(a.Source) - sourceRank(b.Source); n != 0 {
+		return n
+	}
+	if n := publishDate(a).Compare(publishDate(b)); n != 0 {
+		return n
+	}
+	return strings.Compare(a.VideoID, b.VideoID)
+}
+
+func sourceRank(source string) int {
+	if i := slices.Index(talkSourceRank, source); i >= 0 {
+		return i
+	}
+	return len(talkSourceRank)
+}
+
+func publishDate(video videoMeta) time.Time {
+	return time.Date(video.PublishYear, video.PublishMonth, video.PublishDay, 0, 0, 0, 0, time.UTC)
+}
+
+// titleWords returns the words of a title as search tokenizes them, leaving
+// out titleNoise.
+func titleWords(title string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range tokenize(title) {
+		if !titleNoise[word] {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// titleSimilarity is the Dice coefficient of two sets of title words.
+func titleSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for word := range a {
+		if b[word] {
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// talkRow is one talk of the results, with the links of every video of it.
+type talkRow struct {
+	video videoMeta
+	links []string
+}
+
+// groupRows folds the filtered videos into one row per talk, in the order the
+// talks first appear. Each row lists the links of all cached videos of the
+// talk, including those the filters left out.
+func groupRows(filtered []videoMeta, videos map[string]videoMeta) []talkRow {
+	links := make(map[string][]string)
+	for _, video := range videos {
+		if video.TalkID != "" {
+			links[video.TalkID] = append(links[video.TalkID], video.ClickURL)
+		}
+	}
+
+	var rows []talkRow
+	seen := make(map[string]bool)
+	for _, video := range filtered {
+		talkID := video.TalkID
+		if talkID == "" {
+			talkID = video.VideoID
+		}
+		if seen[talkID] {
+			continue
+		}
+		seen[talkID] = true
+
+		row := talkRow{video: video, links: []string{video.ClickURL}}
+		if canonical, ok := videos[talkID]; ok && canonical.TalkID == talkID {
+			row.video = canonical
+		}
+		if talkLinks, ok := links[talkID]; ok {
+			row.links = slices.Sorted(slices.Values(talkLinks))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
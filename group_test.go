@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupTalks(t *testing.T) {
+	c := videoCache{Videos: map[string]videoMeta{
+		"tt1": {VideoID: "tt1", Name: "Peace Is Possible", Source: "tt", VideoDuration: 30 * time.Minute,
+			PublishYear: 2025, PublishMonth: time.March, PublishDay: 10, ClickURL: "https://tt/tt1"},
+		"yt1": {VideoID: "yt1", Name: "Peace is possible | Official Video", Source: "youtube", VideoDuration: 31 * time.Minute,
+			PublishYear: 2025, PublishMonth: time.February, PublishDay: 20, ClickURL: "https://yt/yt1"},
+		"sp1": {VideoID: "sp1", Name: "Peace Is Possible (Audio)", Source: "spotify", VideoDuration: 29 * time.Minute,
+			PublishYear: 2025, PublishMonth: time.April, PublishDay: 1, ClickURL: "https://sp/sp1"},
+		// Same title but far too long to be the same talk.
+		"yt2": {VideoID: "yt2", Name: "Peace Is Possible", Source: "youtube", VideoDuration: time.Hour,
+			PublishYear: 2025, PublishMonth: time.March, PublishDay: 12, ClickURL: "https://yt/yt2"},
+		// Same title and length but published a year later.
+		"yt3": {VideoID: "yt3", Name: "Peace Is Possible", Source: "youtube", VideoDuration: 30 * time.Minute,
+			PublishYear: 2026, PublishMonth: time.March, PublishDay: 10, ClickURL: "https://yt/yt3"},
+		"gone": {VideoID: "gone", Name: "Peace Is Possible", Source: "tt", VideoDuration: 30 * time.Minute,
+			PublishYear: 2025, PublishMonth: time.March, PublishDay: 10, RemovedAt: time.Now(), TalkID: "tt1"},
+	}}
+
+	assert.Equal(t, 3, groupTalks(&c))
+	assert.Equal(t, "tt1", c.Videos["tt1"].TalkID)
+	assert.Equal(t, "tt1", c.Videos["yt1"].TalkID)
+	assert.Equal(t, "tt1", c.Videos["sp1"].TalkID)
+	assert.Equal(t, "yt2", c.Videos["yt2"].TalkID)
+	assert.Equal(t, "yt3", c.Videos["yt3"].TalkID)
+	assert.Empty(t, c.Videos["gone"].TalkID)
+
+	rows := groupRows([]videoMeta{c.Videos["sp1"], c.Videos["yt1"], c.Videos["yt2"]}, c.Videos)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "tt1", rows[0].video.VideoID)
+	assert.Equal(t, []string{"https://sp/sp1", "https://tt/tt1", "https://yt/yt1"}, rows[0].links)
+	assert.Equal(t, []string{"https://yt/yt2"}, rows[1].links)
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, titleSimilarity(titleWords("प्रेम रावत: शांति"), titleWords("शांति | प्रेम रावत")))
+	assert.Less(t, titleSimilarity(titleWords("Part 1: Peace"), titleWords("Part 2: Peace")), talkTitleSimilarity)
+}
+
+func TestGroupSpotifyEpisodes(t *testing.T) {
+	// Spotify only shows a weekday, so episodes get a made up date far from
+	// the day the talk was published elsewhere.
+	c := videoCache{Videos: map[string]videoMeta{
+		"tt1": {VideoID: "tt1", Name: "Hear Yourself", Source: "tt", VideoDuration: 40 * time.Minute,
+			PublishYear: 2023, PublishMonth: time.June, PublishDay: 1},
+		// A talk of the same title and length from years before, which the
+		// episode must not merge into the same talk.
+		"yt1": {VideoID: "yt1", Name: "Hear Yourself", Source: "youtube", VideoDuration: 40 * time.Minute,
+			PublishYear: 2021, PublishMonth: time.May, PublishDay: 1},
+		"sp1": {VideoID: "sp1", Name: "Hear Yourself", Source: "spotify", VideoDuration: 39 * time.Minute,
+			PublishYear: 2026, PublishMonth: time.January, PublishDay: 25},
+	}}
+
+	assert.Equal(t, 2, groupTalks(&c))
+	assert.Equal(t, "tt1", c.Videos["sp1"].TalkID)
+	assert.Equal(t, "yt1", c.Videos["yt1"].TalkID)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxAttempts is how often a single request is tried before giving up.
+	maxAttempts = 5
+	// maxRetryDelay caps the backoff and the Retry-After delay we are
+	// willing to wait for, longer Retry-After values fail the request.
+	maxRetryDelay = time.Minute
+	// defaultRetryBudget is the retry budget of a run unless set by a flag.
+	defaultRetryBudget = 50
+)
+
+var (
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+
+	// retryBaseDelay is the backoff before the first retry, doubled for each
+	// following one.
+	retryBaseDelay = 500 * time.Millisecond
+
+	// retryBudget is how many retries are left for the whole run, shared by
+	// every request so a broken API cannot stall a refresh for long.
+	retryBudget atomic.Int64
+)
+
+// httpGet sends a GET request through doRequest.
+func httpGet(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return doRequest(req)
+}
+
+// doRequest sends req with httpClient, retrying timeouts, 429 and 5xx
+// responses with jittered exponential backoff while the run's retry budget
+// lasts. A Retry-After header on the response takes precedence over the
+// backoff. The last response is returned when retries run out so callers can
+// report its status as before. Requests must not have a body.
+func doRequest(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := httpClient.Do(req)
+		if !shouldRetry(resp, err) || attempt == maxAttempts {
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = after
+			}
+			drain(resp)
+		}
+		if delay > maxRetryDelay {
+			return nil, fmt.Errorf("server asked to retry after %v, giving up", delay)
+		}
+
+		if retryBudget.Add(-1) < 0 {
+			log.Printf("retry budget exhausted, not retrying request to [%v]\n", req.URL.Host)
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("retry budget exhausted after bad http status [%v]", resp.Status)
+		}
+
+		reason := "timeout"
+		if resp != nil {
+			reason = resp.Status
+		}
+		log.Printf("retrying request to [%v] in %v after %v (attempt %v of %v)\n",
+			req.URL.Host, delay.Round(time.Millisecond), reason, attempt+1, maxAttempts)
+		time.Sleep(delay)
+	}
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// backoff returns a random delay of up to retryBaseDelay * 2^(attempt-1).
+func backoff(attempt int) time.Duration {
+	ceiling := min(retryBaseDelay<<(attempt-1), maxRetryDelay)
+	return rand.N(ceiling) + 1
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(at)), true
+	}
+	return 0, false
+}
+
+// drain reads and closes the body of a response that is thrown away, so the
+// connection can be reused for the retry.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if err := resp.Body.Close(); err != nil {
+		log.Printf("error while closing response body: %v", err)
+	}
+}
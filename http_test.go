@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubRetries sets the retry globals for a test, restoring them after it.
+func stubRetries(t *testing.T, budget int64) {
+	savedDelay, savedBudget := retryBaseDelay, retryBudget.Load()
+	t.Cleanup(func() {
+		retryBaseDelay = savedDelay
+		retryBudget.Store(savedBudget)
+	})
+	retryBaseDelay = time.Millisecond
+	retryBudget.Store(budget)
+}
+
+func TestDoRequestRetries(t *testing.T) {
+	stubRetries(t, 10)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := httpGet(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, int64(8), retryBudget.Load())
+}
+
+func TestDoRequestDoesNotRetryClientErrors(t *testing.T) {
+	stubRetries(t, 10)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	resp, err := httpGet(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryAfter(t *testing.T) {
+	d, ok := retryAfter("120")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	_, ok = retryAfter("")
+	assert.False(t, ok)
+
+	_, ok = retryAfter("soon")
+	assert.False(t, ok)
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	indexFile = "index.json"
+
+	// bm25K1 and bm25B are the usual BM25 term frequency saturation and
+	// length normalization.
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// titleWeight is how many description words a title word counts as.
+	titleWeight = 3
+	// phoneticWeight is how much a word that only sounds like a query word
+	// counts, compared to one spelled like it.
+	phoneticWeight = 0.5
+
+	snippetWordsBefore = 8
+	snippetWords       = 24
+)
+
+// searchIndex is an inverted index of the titles and descriptions of the
+// cached videos, kept in indexFile next to the cache and brought up to date
+// with it on every refresh and search.
+type searchIndex struct {
+	Docs map[string]indexedDoc `json:"docs"`
+	// Postings are the videos each term is in, with how often.
+	Postings map[string]map[string]termFreq `json:"postings"`
+}
+
+// indexedDoc is an indexed video. Hash is of its title and description, so a
+// video is only indexed again when they change.
+type indexedDoc struct {
+	Hash     uint64   `json:"hash"`
+	TitleLen int      `json:"titleLen"`
+	DescLen  int      `json:"descLen"`
+	Terms    []string `json:"terms"`
+}
+
+type termFreq struct {
+	Title int `json:"t,omitempty"`
+	Desc  int `json:"d,omitempty"`
+}
+
+func loadIndex() (*searchIndex, error) {
+	idx := &searchIndex{Docs: make(map[string]indexedDoc), Postings: make(map[string]map[string]termFreq)}
+	data, err := os.ReadFile(indexFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading index file [%v]: %w", indexFile, err)
+	}
+
+	if err := json.Unmarshal(data, idx); err != nil {
+		log.Printf("error unmarshalling index file [%v], rebuilding it: %v\n", indexFile, err)
+		return &searchIndex{Docs: make(map[string]indexedDoc), Postings: make(map[string]map[string]termFreq)}, nil
+	}
+	return idx, nil
+}
+
+func (idx *searchIndex) save() error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("error marshalling index: %w", err)
+	}
+	if err := writeFileAtomic(indexFile, data); err != nil {
+		return fmt.Errorf("error writing index file: %w", err)
+	}
+	return nil
+}
+
+// refreshIndex loads the index, brings it up to date with the cached videos
+// and saves it if anything changed. Searching and serving only read the cache,
+// so unless mustSave is set a failure to save is logged and the index is used
+// as it is in memory.
+func refreshIndex(videos map[string]videoMeta, mustSave bool) (*searchIndex, error) {
+	idx, err := loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	added, updated, removed := idx.update(videos)
+	if added+updated+removed == 0 {
+		return idx, nil
+	}
+
+	log.Printf("indexed videos: %v added, %v updated, %v removed\n", added, updated, removed)
+	if err := idx.save(); err != nil {
+		if mustSave {
+			return nil, err
+		}
+		log.Printf("error saving search index, using it unsaved: %v\n", err)
+	}
+	return idx, nil
+}
+
+// update indexes the videos that are new or whose title or description
+// changed, and drops videos that are gone, removed or excluded.
+func (idx *searchIndex) update(videos map[string]videoMeta) (added, updated, removed int) {
+	for id := range idx.Docs {
+		if video, ok := videos[id]; !ok || !searchable(video) {
+			idx.remove(id)
+			removed++
+		}
+	}
+
+	for id, video := range videos {
+		if !searchable(video) {
+			continue
+		}
+		hash := textHash(video)
+		doc, ok := idx.Docs[id]
+		if ok && doc.Hash == hash {
+			continue
+		}
+		if ok {
+			idx.remove(id)
+			updated++
+		} else {
+			added++
+		}
+		idx.add(id, video, hash)
+	}
+	return added, updated, removed
+}
+
+func searchable(video videoMeta) bool {
+	return video.RemovedAt.IsZero() && video.Excluded == ""
+}
+
+func textHash(video videoMeta) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(video.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(video.Description))
+	return h.Sum64()
+}
+
+func (idx *searchIndex) add(id string, video videoMeta, hash uint64) {
+	doc := newSearchDoc(video)
+	freqs := make(map[string]termFreq)
+	for _, word := range doc.title {
+		f := freqs[word]
+		f.Title++
+		freqs[word] = f
+	}
+	for _, word := range doc.desc {
+		f := freqs[word]
+		f.Desc++
+		freqs[word] = f
+	}
+
+	terms := make([]string, 0, len(freqs))
+	for term, f := range freqs {
+		if idx.Postings[term] == nil {
+			idx.Postings[term] = make(map[string]termFreq)
+		}
+		idx.Postings[term][id] = f
+		terms = append(terms, term)
+	}
+	slices.Sort(terms)
+	idx.Docs[id] = indexedDoc{Hash: hash, TitleLen: len(doc.title), DescLen: len(doc.desc), Terms: terms}
+}
+
+func (idx *searchIndex) remove(id string) {
+	for _, term := range idx.Docs[id].Terms {
+		delete(idx.Postings[term], id)
+		if len(idx.Postings[term]) == 0 {
+			delete(idx.Postings, term)
+		}
+	}
+	delete(idx.Docs, id)
+}
+
+// scoringTerms returns the terms of a query that make a video more relevant,
+// which are all of them but those under a NOT.
+func scoringTerms(node queryNode) []termNode {
+	switch n := node.(type) {
+	case termNode:
+		return []termNode{n}
+	case andNode:
+		var terms []termNode
+		for _, child := range n {
+			terms = append(terms, scoringTerms(child)...)
+		}
+		return terms
+	case orNode:
+		var terms []termNode
+		for _, child := range n {
+			terms = append(terms, scoringTerms(child)...)
+		}
+		return terms
+	default:
+		return nil
+	}
+}
+
+// expand returns the index terms the i-th word of a query term matches, with
+// whether they only match by their phonetic key.
+func (idx *searchIndex) expand(term termNode, i int) map[string]bool {
+	terms := make(map[string]bool)
+	for indexed := range idx.Postings {
+		if indexed == term.words[i] || !term.phrase && strings.HasPrefix(indexed, term.words[i]) {
+			terms[indexed] = false
+		} else if acrossScripts(indexed, term.words[i]) && phoneticKey(indexed) == term.keys[i] {
+			terms[indexed] = true
+		}
+	}
+	return terms
+}
+
+// searchHit is a video matching a query, with its BM25 score and a snippet of
+// where it matched.
+type searchHit struct {
+	video   videoMeta
+	score   float64
+	snippet string
+}
+
+// rank scores the videos for the query with BM25, counting title words
+// titleWeight times, and returns them best first.
+func (idx *searchIndex) rank(videos []videoMeta, query queryNode) []searchHit {
+	var totalLen float64
+	for _, doc := range idx.Docs {
+		totalLen += float64(titleWeight*doc.TitleLen + doc.DescLen)
+	}
+	avgLen := max(totalLen/float64(max(len(idx.Docs), 1)), 1)
+	n := float64(len(idx.Docs))
+
+	// Each query word is looked up once for all videos, along with the
+	// words it starts.
+	type scoredTerm struct {
+		field    string
+		postings map[string]termFreq
+		idf      float64
+	}
+	terms := scoringTerms(query)
+	var scored []scoredTerm
+	for _, term := range terms {
+		for i := range term.words {
+			for indexed, phonetic := range idx.expand(term, i) {
+				postings := idx.Postings[indexed]
+				df := float64(len(postings))
+				idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+				if phonetic {
+					idf *= phoneticWeight
+				}
+				scored = append(scored, scoredTerm{field: term.field, postings: postings, idf: idf})
+			}
+		}
+	}
+
+	hits := make([]searchHit, 0, len(videos))
+	for _, video := range videos {
+		doc := idx.Docs[video.VideoID]
+		docLen := float64(titleWeight*doc.TitleLen + doc.DescLen)
+
+		var score float64
+		for _, term := range scored {
+			f := term.postings[video.VideoID]
+			var tf float64
+			switch term.field {
+			case "title":
+				tf = float64(titleWeight * f.Title)
+			case "desc":
+				tf = float64(f.Desc)
+			default:
+				tf = float64(titleWeight*f.Title + f.Desc)
+			}
+			if tf > 0 {
+				score += term.idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*docLen/avgLen))
+			}
+		}
+		hits = append(hits, searchHit{video: video, score: score, snippet: snippet(video, terms)})
+	}
+
+	slices.SortStableFunc(hits, func(a, b searchHit) int {
+		if a.score != b.score {
+			if a.score > b.score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.video.VideoID, b.video.VideoID)
+	})
+	return hits
+}
+
+// snippet returns the part of the description around the first word the
+// query matched, or the title when it matched none there, with the matched
+// words in **.
+func snippet(video videoMeta, terms []termNode) string {
+	text := video.Description
+	spans := wordSpans(text)
+	first := slices.IndexFunc(spans, func(s wordSpan) bool { return matchesAny(s.word, terms) })
+	if first < 0 {
+		text = video.Name
+		spans = wordSpans(text)
+		first = max(slices.IndexFunc(spans, func(s wordSpan) bool { return matchesAny(s.word, terms) }), 0)
+	}
+	if len(spans) == 0 {
+		return ""
+	}
+
+	lo := max(first-snippetWordsBefore, 0)
+	hi := min(lo+snippetWords, len(spans))
+	var b strings.Builder
+	if lo > 0 {
+		b.WriteString("…")
+	}
+	at := spans[lo].start
+	for _, s := range spans[lo:hi] {
+		b.WriteString(text[at:s.start])
+		if matchesAny(s.word, terms) {
+			b.WriteString("**" + text[s.start:s.end] + "**")
+		} else {
+			b.WriteString(text[s.start:s.end])
+		}
+		at = s.end
+	}
+	if hi < len(spans) {
+		b.WriteString("…")
+	} else {
+		b.WriteString(text[at:])
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func matchesAny(word string, terms []termNode) bool {
+	key := phoneticKey(word)
+	return slices.ContainsFunc(terms, func(term termNode) bool {
+		for i := range term.words {
+			if term.wordMatches(i, word, key) {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchIndex(t *testing.T) {
+	videos := map[string]videoMeta{
+		"title": {VideoID: "title", Name: "Breath of life", Description: "A talk given in Delhi."},
+		"desc":  {VideoID: "desc", Name: "Questions and answers", Description: "Answers about peace, and about the breath we take."},
+		"none":  {VideoID: "none", Name: "Peace", Description: "Nothing else."},
+		"gone":  {VideoID: "gone", Name: "Breath", RemovedAt: time.Now()},
+	}
+
+	idx := &searchIndex{Docs: map[string]indexedDoc{}, Postings: map[string]map[string]termFreq{}}
+	added, updated, removed := idx.update(videos)
+	assert.Equal(t, []int{3, 0, 0}, []int{added, updated, removed})
+	assert.NotContains(t, idx.Postings["breath"], "gone")
+
+	query, err := parseQuery("breath")
+	assert.NoError(t, err)
+	hits := idx.rank([]videoMeta{videos["desc"], videos["title"]}, query)
+	assert.Equal(t, "title", hits[0].video.VideoID)
+	assert.Greater(t, hits[0].score, hits[1].score)
+	assert.Equal(t, "**Breath** of life", hits[0].snippet)
+	assert.Equal(t, "Answers about peace, and about the **breath** we take.", hits[1].snippet)
+
+	videos["title"] = videoMeta{VideoID: "title", Name: "Life", Description: "A talk given in Delhi."}
+	delete(videos, "none")
+	added, updated, removed = idx.update(videos)
+	assert.Equal(t, []int{0, 1, 1}, []int{added, updated, removed})
+	assert.Equal(t, map[string]termFreq{"desc": {Desc: 1}}, idx.Postings["breath"])
+	assert.NotContains(t, idx.Postings, "nothing")
+}
+
+func TestRefreshIndexWithoutSaving(t *testing.T) {
+	// index.json cannot be written in a directory that is gone.
+	dir := t.TempDir()
+	t.Chdir(dir)
+	assert.NoError(t, os.Remove(dir))
+	videos := map[string]videoMeta{"a": {VideoID: "a", Name: "Breath"}}
+
+	idx, err := refreshIndex(videos, false)
+	assert.NoError(t, err, "searching only reads the cache")
+	assert.Contains(t, idx.Postings["breath"], "a")
+
+	_, err = refreshIndex(videos, true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// languages are the languages content is fetched and kept in, written the way
+// TT writes them. Set with -languages or the languages of the config file.
+var languages = []string{hindiLang, englishLang}
+
+// devanagariLangs are languages written in Devanagari, titles in that script
+// are taken to be Hindi unless YouTube says they are in one of the others and
+// it is one of languages.
+var devanagariLangs = []string{"hi", "mr", "ne"}
+
+var languageCode = regexp.MustCompile(`^[a-z]{2,3}-[A-Z]{2}$`)
+
+// parseLanguages parses a comma separated list of language codes such as
+// "hi-IN,en-US,es-ES".
+func parseLanguages(spec string) ([]string, error) {
+	var langs []string
+	for _, lang := range strings.Split(spec, ",") {
+		lang = strings.TrimSpace(lang)
+		if lang == "" {
+			continue
+		}
+		if err := checkLanguageCode(lang); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(langs, lang) {
+			langs = append(langs, lang)
+		}
+	}
+	if len(langs) == 0 {
+		return nil, fmt.Errorf("no language in [%v]", spec)
+	}
+	return langs, nil
+}
+
+func checkLanguageCode(lang string) error {
+	if !languageCode.MatchString(lang) {
+		return fmt.Errorf("invalid language [%v], expected such as hi-IN or es-ES", lang)
+	}
+	return nil
+}
+
+func supportedLang(lang string) bool {
+	return slices.Contains(languages, lang)
+}
+
+// normalizeLang maps a language as YouTube or a user writes it, such as "hi",
+// "en-GB" or "es-419", to the configured language sharing its primary code.
+// Languages that match none are returned as they are.
+func normalizeLang(lang string) string {
+	if lang == "" || supportedLang(lang) {
+		return lang
+	}
+
+	primary := getLangTwoLetterCode(lang)
+	for _, configured := range languages {
+		if getLangTwoLetterCode(configured) == primary {
+			return configured
+		}
+	}
+	return lang
+}
+
+// getLangTwoLetterCode returns the primary language code, such as "hi" for
+// "hi-IN", used in TT URLs.
+func getLangTwoLetterCode(lang string) string {
+	primary, _, _ := strings.Cut(lang, "-")
+	if primary == "" {
+		return "en"
+	}
+	return strings.ToLower(primary)
+}
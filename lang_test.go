@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguages(t *testing.T) {
+	defer func(langs []string) { languages = langs }(languages)
+
+	langs, err := parseLanguages("hi-IN, en-US,es-ES,hi-IN")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{hindiLang, englishLang, "es-ES"}, langs)
+	_, err = parseLanguages("hi-IN,spanish")
+	assert.EqualError(t, err, "invalid language [spanish], expected such as hi-IN or es-ES")
+
+	languages = []string{hindiLang, englishLang, "es-ES", "mr-IN"}
+	assert.Equal(t, "es-ES", normalizeLang("es-419"))
+	assert.Equal(t, englishLang, normalizeLang("en-GB"))
+	assert.Equal(t, "fr", normalizeLang("fr"))
+	assert.Equal(t, "mr-IN", langTT("mr", "ध्यान"))
+	assert.Equal(t, hindiLang, langTT("en", "ध्यान"))
+	assert.Equal(t, "es-ES", langTT("es", "Meditación"))
+	assert.Equal(t, "https://www.timelesstoday.tv/es/home/product/x", getClickURL("x", "es-ES"))
+
+	languages = []string{hindiLang, englishLang}
+	assert.Equal(t, hindiLang, langTT("mr", "ध्यान"), "marathi is not configured")
+	assert.Equal(t, hindiLang, langTT("ne", "ध्यान"))
+}
+
+func TestMatchesLanguages(t *testing.T) {
+	dubbed := videoMeta{Language: englishLang, AudioLanguages: []string{hindiLang}}
+	subtitled := videoMeta{Language: englishLang, SubtitleLanguages: []string{hindiLang}}
+
+	assert.True(t, matchesLanguages(dubbed, "", ""))
+	assert.True(t, matchesLanguages(dubbed, hindiLang, ""))
+	assert.True(t, matchesLanguages(dubbed, englishLang, ""))
+	assert.False(t, matchesLanguages(dubbed, "", hindiLang))
+	assert.False(t, matchesLanguages(subtitled, hindiLang, ""))
+	assert.True(t, matchesLanguages(subtitled, hindiLang, hindiLang))
+}
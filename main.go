@@ -1,8 +1,12 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -13,69 +17,165 @@ type videoMeta struct {
 	Name          string
 	Description   string
 	VideoDuration time.Duration
-	Language      string
-	ClickURL      string
-	PublishYear   int
-	PublishMonth  time.Month
-	PublishDay    int
-	ThumbnailURL  string
-	AudioOnly     bool
+	// Language is the language the talk was given in.
+	Language string
+	// AudioLanguages are the languages the talk is dubbed in, besides Language,
+	// and SubtitleLanguages the ones it has subtitles in. No source reports
+	// them, they are set by the overrides file.
+	AudioLanguages    []string `json:",omitempty"`
+	SubtitleLanguages []string `json:",omitempty"`
+	ClickURL          string
+	PublishYear       int
+	PublishMonth      time.Month
+	PublishDay        int
+	ThumbnailURL      string
+	AudioOnly         bool
+	Source            string
+	Channel           string   `json:",omitempty"`
+	Tags              []string `json:",omitempty"`
+	Excluded          string   `json:",omitempty"`
+	// TalkID is the VideoID of the canonical video of the talk this is one
+	// of, see groupTalks.
+	TalkID        string `json:",omitempty"`
+	LastSeen      time.Time
+	RemovedAt     time.Time `json:",omitzero"`
+	MetaFetchedAt time.Time `json:",omitzero"`
 }
 
 type filterParam struct {
+	query       string
 	lang        string
+	audioLang   string
+	subLang     string
 	durationMin time.Duration
 	durationMax time.Duration
 	publishYear int
-	source      string
+	audioOnly   bool
+	// from and to are the first and last publish day, zero for no bound.
+	from   time.Time
+	to     time.Time
+	source string
 }
 
 func main() {
-	lang := flag.String("lang", "", "filter by language [en-US, hi-IN]")
-	durationMin := flag.Duration("minDuration", 0, "filter by minimum duration [such as 30s, 20m, 1h]")
-	durationMax := flag.Duration("maxDuration", 0, "filter by maximum duration [such as 30s, 20m, 1h]")
-	publishYear := flag.Int("publishYear", 0, "filter by publish year [such as 2022, 2023, 2024]")
-	source := flag.String("source", "", "filter by source [youtube, tt]")
-	updateCache := flag.Bool("update", false, "update cache before filtering")
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+	os.Exit(exitStatus(runLegacy()))
+}
+
+// runLegacy runs disha without a command, taking the flags of search and
+// update together as older versions did.
+func runLegacy() error {
+	var settings settingsFlags
+	settings.register(flag.CommandLine)
+	var update updateFlags
+	update.register(flag.CommandLine)
+	var filters filterFlags
+	filters.register(flag.CommandLine)
+	var output outputFlags
+	output.register(flag.CommandLine, "text")
+	updateCache := flag.Bool("update", false, "update cache before filtering, prefer disha update")
+	flag.Usage = func() {
+		printUsage(flag.CommandLine.Output())
+		fmt.Fprintf(flag.CommandLine.Output(), "\nflags without a command:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	writeResults, err := newResultWriter(output.format, output.options)
+	if err != nil {
+		return err
+	}
+	if err := settings.apply(); err != nil {
+		return err
+	}
+	if err := update.apply(); err != nil {
+		return err
+	}
+
 	if err := cache.setup(*updateCache); err != nil {
-		panic(err)
+		var refreshErr *refreshError
+		if !errors.As(err, &refreshErr) || *updateCache {
+			return err
+		}
+		log.Printf("%v, kept their last known videos:\n%v\n", refreshErr, refreshErr.summary())
 	}
 
 	if *updateCache {
-		return
+		return nil
 	}
 
-	if *source != "" && *source == "tt" {
-		*source = "timelesstoday"
+	params, err := filters.params(time.Now())
+	if err != nil {
+		return err
 	}
-	params := filterParam{
-		lang:        *lang,
-		durationMin: *durationMin,
-		durationMax: *durationMax,
-		publishYear: *publishYear,
-		source:      *source,
+	rows, err := findResults(cache.Videos, params, output.group)
+	if err != nil {
+		return err
 	}
+	return writeResults(os.Stdout, rows)
+}
 
-	filteredVideos, err := filterContent(cache.Videos, params)
+// findResults filters the videos, ranked by relevance when searching and
+// latest first otherwise. With group there is one row per talk instead.
+func findResults(videos map[string]videoMeta, params filterParam, group bool) ([]resultRow, error) {
+	filteredVideos, err := filterContent(videos, params)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	log.Println("total filtered videos latest to oldest:", len(filteredVideos))
+	var rows []resultRow
+	if params.query != "" {
+		hits, err := searchVideos(filteredVideos, videos, params.query)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("total matching videos by relevance:", len(hits))
 
-	for _, video := range filteredVideos {
-		log.Printf("[%v] in [%v-%v] of [%v]: %v\n", video.Name, video.PublishMonth,
-			video.PublishYear, video.VideoDuration, video.ClickURL)
+		filteredVideos = filteredVideos[:0]
+		for _, hit := range hits {
+			row := newResultRow(hit.video)
+			row.Score, row.Snippet, row.ranked = hit.score, hit.snippet, true
+			rows = append(rows, row)
+			filteredVideos = append(filteredVideos, hit.video)
+		}
+	} else {
+		log.Println("total filtered videos latest to oldest:", len(filteredVideos))
+		for _, video := range filteredVideos {
+			rows = append(rows, newResultRow(video))
+		}
 	}
+
+	if group {
+		talks := groupRows(filteredVideos, videos)
+		log.Println("total talks:", len(talks))
+		rows = rows[:0]
+		for _, talk := range talks {
+			row := newResultRow(talk.video)
+			row.Links = talk.links
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
 }
 
 func filterContent(videos map[string]videoMeta, param filterParam) ([]videoMeta, error) {
+	query, err := parseQuery(param.query)
+	if err != nil {
+		return nil, err
+	}
+
 	var filteredVideos []videoMeta
 	for _, video := range videos {
+		if !video.RemovedAt.IsZero() || video.Excluded != "" {
+			continue
+		}
 		if param.lang != "" && video.Language != param.lang {
 			continue
 		}
+		if !matchesLanguages(video, param.audioLang, param.subLang) {
+			continue
+		}
 		if param.durationMin != 0 && video.VideoDuration < param.durationMin {
 			continue
 		}
@@ -85,9 +185,18 @@ func filterContent(videos map[string]videoMeta, param filterParam) ([]videoMeta,
 		if param.publishYear != 0 && video.PublishYear != param.publishYear {
 			continue
 		}
+		if param.audioOnly && !video.AudioOnly {
+			continue
+		}
+		if day := publishDate(video); !param.from.IsZero() && day.Before(param.from) || !param.to.IsZero() && day.After(param.to) {
+			continue
+		}
 		if param.source != "" && !strings.Contains(video.ClickURL, param.source) {
 			continue
 		}
+		if query != nil && !query.match(newSearchDoc(video)) {
+			continue
+		}
 
 		filteredVideos = append(filteredVideos, video)
 	}
@@ -95,12 +204,42 @@ func filterContent(videos map[string]videoMeta, param filterParam) ([]videoMeta,
 	return sortVideosByPublishYear(filteredVideos), nil
 }
 
+// searchVideos ranks the filtered videos for the query, using the search index
+// brought up to date with all the cached videos.
+func searchVideos(videos []videoMeta, all map[string]videoMeta, query string) ([]searchHit, error) {
+	node, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := refreshIndex(all, false)
+	if err != nil {
+		return nil, err
+	}
+	return idx.rank(videos, node), nil
+}
+
+// matchesLanguages reports whether the video can be heard in audioLang or has
+// subtitles in subLang. Either can be empty, so that a listener who follows
+// Hindi audio as well as Hindi subtitles can ask for both at once.
+func matchesLanguages(video videoMeta, audioLang, subLang string) bool {
+	if audioLang == "" && subLang == "" {
+		return true
+	}
+	if audioLang != "" && (video.Language == audioLang || slices.Contains(video.AudioLanguages, audioLang)) {
+		return true
+	}
+	return subLang != "" && slices.Contains(video.SubtitleLanguages, subLang)
+}
+
 func sortVideosByPublishYear(videos []videoMeta) []videoMeta {
 	sort.Slice(videos, func(i, j int) bool {
 		if videos[i].PublishYear != videos[j].PublishYear {
 			return videos[i].PublishYear > videos[j].PublishYear
 		}
-		return videos[i].PublishMonth > videos[j].PublishMonth
+		if videos[i].PublishMonth != videos[j].PublishMonth {
+			return videos[i].PublishMonth > videos[j].PublishMonth
+		}
+		return videos[i].PublishDay > videos[j].PublishDay
 	})
 	return videos
 }
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// outputFormats are the values of -format. text is the log lines written to
+// stderr, the others are written to stdout.
+var outputFormats = []string{"text", "json", "ndjson", "csv", "tsv", "markdown", "template", "m3u8", "xspf", "rss", "atom"}
+
+// outputOptions are the flags that only some formats use.
+type outputOptions struct {
+	// template is run for every row by the template format.
+	template string
+	// feedTitle and feedLink describe the rss and atom feeds.
+	feedTitle string
+	feedLink  string
+}
+
+// resultRow is a video of the results as -format writes it, with how it
+// matched a search and the links of its talk when grouped. It only has the
+// fields worth publishing, not the cache's own bookkeeping.
+type resultRow struct {
+	VideoID     string
+	Name        string
+	Description string `json:",omitempty"`
+	// Duration is the length of the video such as 43m49s, and Seconds the
+	// same in seconds.
+	Duration          string
+	Seconds           int
+	Language          string
+	AudioLanguages    []string `json:",omitempty"`
+	SubtitleLanguages []string `json:",omitempty"`
+	ClickURL          string
+	ThumbnailURL      string `json:",omitempty"`
+	AudioOnly         bool
+	Source            string
+	Channel           string   `json:",omitempty"`
+	Tags              []string `json:",omitempty"`
+	// Published is the publish day, such as 2025-11-21.
+	Published string
+	Score     float64  `json:",omitempty"`
+	Snippet   string   `json:",omitempty"`
+	Links     []string `json:",omitempty"`
+
+	video  videoMeta
+	ranked bool
+}
+
+func newResultRow(video videoMeta) resultRow {
+	return resultRow{
+		VideoID:           video.VideoID,
+		Name:              video.Name,
+		Description:       video.Description,
+		Duration:          video.VideoDuration.String(),
+		Seconds:           int(video.VideoDuration.Seconds()),
+		Language:          video.Language,
+		AudioLanguages:    video.AudioLanguages,
+		SubtitleLanguages: video.SubtitleLanguages,
+		ClickURL:          video.ClickURL,
+		ThumbnailURL:      video.ThumbnailURL,
+		AudioOnly:         video.AudioOnly,
+		Source:            video.Source,
+		Channel:           video.Channel,
+		Tags:              video.Tags,
+		Published:         publishDate(video).Format(time.DateOnly),
+		video:             video,
+	}
+}
+
+// resultWriter writes the rows of the results in one format.
+type resultWriter func(w io.Writer, rows []resultRow) error
+
+// newResultWriter returns the writer for a -format.
+func newResultWriter(format string, opts outputOptions) (resultWriter, error) {
+	switch format {
+	case "", "text":
+		return writeText, nil
+	case "json":
+		return writeJSON, nil
+	case "ndjson":
+		return writeNDJSON, nil
+	case "csv":
+		return writeDelimited(','), nil
+	case "tsv":
+		return writeDelimited('\t'), nil
+	case "markdown":
+		return writeMarkdown, nil
+	case "m3u8":
+		return writeM3U8, nil
+	case "xspf":
+		return writeXSPF, nil
+	case "rss":
+		return writeRSS(opts), nil
+	case "atom":
+		return writeAtom(opts), nil
+	case "template":
+		if opts.template == "" {
+			return nil, fmt.Errorf("format [template] needs a -template such as '{{.Name}}: {{.ClickURL}}'")
+		}
+		t, err := template.New("row").Funcs(template.FuncMap{"join": strings.Join}).Parse(opts.template)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing -template: %w", err)
+		}
+		return writeTemplate(t), nil
+	default:
+		return nil, fmt.Errorf("unknown format [%v], expected one of %v", format, outputFormats)
+	}
+}
+
+func writeText(_ io.Writer, rows []resultRow) error {
+	for _, row := range rows {
+		switch {
+		case row.ranked:
+			log.Printf("[%.2f] [%v] in [%v-%v] of [%v]: %v\n    %v\n", row.Score, row.Name, row.video.PublishMonth,
+				row.video.PublishYear, row.Duration, row.ClickURL, row.Snippet)
+		case len(row.Links) > 0:
+			log.Printf("[%v] in [%v-%v] of [%v]: %v\n", row.Name, row.video.PublishMonth,
+				row.video.PublishYear, row.Duration, strings.Join(row.Links, " "))
+		default:
+			log.Printf("[%v] in [%v-%v] of [%v]: %v\n", row.Name, row.video.PublishMonth,
+				row.video.PublishYear, row.Duration, row.ClickURL)
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, rows []resultRow) error {
+	if rows == nil {
+		rows = []resultRow{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rows); err != nil {
+		return fmt.Errorf("error writing json: %w", err)
+	}
+	return nil
+}
+
+func writeNDJSON(w io.Writer, rows []resultRow) error {
+	enc := json.NewEncoder(w)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return fmt.Errorf("error writing json for video [%v]: %w", row.VideoID, err)
+		}
+	}
+	return nil
+}
+
+// delimitedHeader are the columns of the csv and tsv formats.
+var delimitedHeader = []string{"published", "name", "duration", "language", "source", "url", "score", "snippet", "links"}
+
+func writeDelimited(comma rune) resultWriter {
+	return func(w io.Writer, rows []resultRow) error {
+		cw := csv.NewWriter(w)
+		cw.Comma = comma
+		if err := cw.Write(delimitedHeader); err != nil {
+			return fmt.Errorf("error writing header: %w", err)
+		}
+		for _, row := range rows {
+			var score string
+			if row.ranked {
+				score = strconv.FormatFloat(row.Score, 'f', 2, 64)
+			}
+			record := []string{row.Published, row.Name, row.Duration, row.Language, row.Source,
+				row.ClickURL, score, row.Snippet, strings.Join(row.Links, " ")}
+			if err := cw.Write(record); err != nil {
+				return fmt.Errorf("error writing video [%v]: %w", row.VideoID, err)
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "[", `\[`, "]", `\]`, "\n", " ")
+
+func writeMarkdown(w io.Writer, rows []resultRow) error {
+	var b strings.Builder
+	b.WriteString("| Published | Talk | Duration | Language | Links |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, row := range rows {
+		links := row.Links
+		if len(links) == 0 {
+			links = []string{row.ClickURL}
+		}
+		fmt.Fprintf(&b, "| %v | %v | %v | %v | %v |\n", row.Published, markdownEscaper.Replace(row.Name),
+			row.Duration, row.Language, strings.Join(links, "<br>"))
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("error writing markdown: %w", err)
+	}
+	return nil
+}
+
+var lineJoiner = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// writeM3U8 writes an extended M3U playlist in UTF-8, which players such as
+// VLC and mpv open directly.
+func writeM3U8(w io.Writer, rows []resultRow) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for _, row := range rows {
+		fmt.Fprintf(&b, "#EXTINF:%d,%v\n%v\n", row.Seconds, lineJoiner.Replace(row.Name), row.ClickURL)
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("error writing m3u8: %w", err)
+	}
+	return nil
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Title   string      `xml:"title"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location   string `xml:"location"`
+	Title      string `xml:"title"`
+	Annotation string `xml:"annotation,omitempty"`
+	Image      string `xml:"image,omitempty"`
+	// Duration is in milliseconds.
+	Duration int64 `xml:"duration,omitempty"`
+}
+
+// writeXSPF writes an XSPF playlist, see https://xspf.org/spec.
+func writeXSPF(w io.Writer, rows []resultRow) error {
+	playlist := xspfPlaylist{Version: "1", Title: "disha", Tracks: []xspfTrack{}}
+	for _, row := range rows {
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{
+			Location:   row.ClickURL,
+			Title:      row.Name,
+			Annotation: row.Description,
+			Image:      row.ThumbnailURL,
+			Duration:   row.video.VideoDuration.Milliseconds(),
+		})
+	}
+	return writeXML(w, "xspf", playlist)
+}
+
+func writeTemplate(t *template.Template) resultWriter {
+	return func(w io.Writer, rows []resultRow) error {
+		for _, row := range rows {
+			if err := t.Execute(w, row); err != nil {
+				return fmt.Errorf("error executing -template for video [%v]: %w", row.VideoID, err)
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
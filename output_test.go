@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultWriters(t *testing.T) {
+	row := newResultRow(videoMeta{
+		VideoID: "abc", Name: "Peace | Possible", VideoDuration: 45 * time.Minute, Language: hindiLang,
+		ClickURL: "https://example.com/abc", Source: "youtube",
+		PublishYear: 2025, PublishMonth: time.November, PublishDay: 2,
+	})
+	write := func(format, tmpl string) string {
+		w, err := newResultWriter(format, outputOptions{template: tmpl, feedTitle: "Disha", feedLink: defaultFeedLink})
+		assert.NoError(t, err)
+		var b bytes.Buffer
+		assert.NoError(t, w(&b, []resultRow{row}))
+		return b.String()
+	}
+
+	assert.Equal(t, "published,name,duration,language,source,url,score,snippet,links\n"+
+		"2025-11-02,Peace | Possible,45m0s,hi-IN,youtube,https://example.com/abc,,,\n", write("csv", ""))
+	assert.Equal(t, "| Published | Talk | Duration | Language | Links |\n| --- | --- | --- | --- | --- |\n"+
+		`| 2025-11-02 | Peace \| Possible | 45m0s | hi-IN | https://example.com/abc |`+"\n", write("markdown", ""))
+	assert.Equal(t, "Peace | Possible (2025-11-02): https://example.com/abc\n",
+		write("template", "{{.Name}} ({{.Published}}): {{.ClickURL}}"))
+	assert.Contains(t, write("ndjson", ""), `"VideoID":"abc"`)
+	assert.Contains(t, write("ndjson", ""), `"Published":"2025-11-02"`)
+	assert.Contains(t, write("ndjson", ""), `"Duration":"45m0s","Seconds":2700`)
+	assert.NotContains(t, write("json", ""), "LastSeen")
+
+	assert.Equal(t, "#EXTM3U\n#EXTINF:2700,Peace | Possible\nhttps://example.com/abc\n", write("m3u8", ""))
+	assert.Equal(t, xml.Header+`<playlist xmlns="http://xspf.org/ns/0/" version="1">
+  <title>disha</title>
+  <trackList>
+    <track>
+      <location>https://example.com/abc</location>
+      <title>Peace | Possible</title>
+      <duration>2700000</duration>
+    </track>
+  </trackList>
+</playlist>
+`, write("xspf", ""))
+
+	rss := write("rss", "")
+	assert.Contains(t, rss, `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">`)
+	assert.Contains(t, rss, "<language>hi-IN</language>")
+	assert.Contains(t, rss, "<pubDate>Sun, 02 Nov 2025 00:00:00 +0000</pubDate>")
+	assert.Contains(t, rss, "<itunes:duration>00:45:00</itunes:duration>")
+	assert.Contains(t, rss, `<guid isPermaLink="false">youtube:abc</guid>`)
+	atom := write("atom", "")
+	assert.Contains(t, atom, `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, atom, "<updated>2025-11-02T00:00:00Z</updated>")
+	assert.Contains(t, atom, `<link href="https://example.com/abc" rel="alternate"></link>`)
+
+	_, err := newResultWriter("xml", outputOptions{})
+	assert.EqualError(t, err, "unknown format [xml], expected one of [text json ndjson csv tsv markdown template m3u8 xspf rss atom]")
+	_, err = newResultWriter("template", outputOptions{})
+	assert.Error(t, err)
+}
@@ -1,218 +1,405 @@
 package main
 
 import (
+	_ "embed"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"slices"
+	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed data/overrides.yaml
+var defaultOverrides []byte
+
+// overrides are the manual corrections applied to the cache after every
+// update, see data/overrides.yaml for the file format.
+var overrides overrideSet
+
+type overrideSet struct {
+	add     []videoOverride
+	patch   []videoOverride
+	remove  []videoOverride
+	exclude []exclusionRule
+}
+
+// videoOverride is one entry of an overrides file. Fields left out of the
+// entry stay nil.
+type videoOverride struct {
+	ID          string  `yaml:"id"`
+	Name        *string `yaml:"name"`
+	Description *string `yaml:"description"`
+	Duration    *string `yaml:"duration"`
+	Language    *string `yaml:"language"`
+	URL         *string `yaml:"url"`
+	Published   *string `yaml:"published"`
+	Thumbnail   *string `yaml:"thumbnail"`
+	AudioOnly   *bool   `yaml:"audioOnly"`
+	// AudioLanguages and SubtitleLanguages replace those of the video.
+	AudioLanguages    []string `yaml:"audioLanguages"`
+	SubtitleLanguages []string `yaml:"subtitleLanguages"`
+
+	line      int
+	duration  time.Duration
+	published time.Time
+}
+
+var (
+	overrideSections = []string{"add", "patch", "remove", "exclude"}
+	overrideFields   = []string{"id", "name", "description", "duration", "language", "url", "published", "thumbnail", "audioOnly", "audioLanguages", "subtitleLanguages"}
+	requiredToAdd    = []string{"name", "duration", "language", "url", "published"}
 )
 
+// loadOverrides reads the overrides file at path, or the embedded
+// data/overrides.yaml when path is empty.
+func loadOverrides(path string) (overrideSet, error) {
+	if path == "" {
+		return parseOverrides("data/overrides.yaml", defaultOverrides)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return overrideSet{}, fmt.Errorf("error reading overrides file [%v]: %w", path, err)
+	}
+	return parseOverrides(path, data)
+}
+
+// parseOverrides decodes and validates an overrides file. Every problem found
+// is reported with the file name and line it is on.
+func parseOverrides(name string, data []byte) (overrideSet, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return overrideSet{}, fmt.Errorf("%v: %w", name, err)
+	}
+	if len(root.Content) == 0 {
+		return overrideSet{}, nil
+	}
+
+	var errs []error
+	fail := func(line int, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%v:%v: %v", name, line, fmt.Sprintf(format, args...)))
+	}
+
+	var set overrideSet
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		fail(doc.Line, "expected a mapping with %v", overrideSections)
+		return overrideSet{}, errors.Join(errs...)
+	}
+
+	for i := 0; i < len(doc.Content); i += 2 {
+		key, value := doc.Content[i], doc.Content[i+1]
+		if !slices.Contains(overrideSections, key.Value) {
+			fail(key.Line, "unknown section [%v], expected one of %v", key.Value, overrideSections)
+			continue
+		}
+		if value.Kind != yaml.SequenceNode {
+			fail(value.Line, "section [%v] should be a list", key.Value)
+			continue
+		}
+
+		if key.Value == "exclude" {
+			for _, item := range value.Content {
+				if rule, ok := decodeExclusion(item, fail); ok {
+					set.exclude = append(set.exclude, rule)
+				}
+			}
+			continue
+		}
+
+		seen := make(map[string]int)
+		for _, item := range value.Content {
+			entry, ok := decodeOverride(item, fail)
+			if !ok {
+				continue
+			}
+			if first, dup := seen[entry.ID]; dup {
+				fail(entry.line, "video [%v] is already listed under [%v] on line %v", entry.ID, key.Value, first)
+				continue
+			}
+			seen[entry.ID] = entry.line
+
+			if !validateOverride(key.Value, &entry, fail) {
+				continue
+			}
+			switch key.Value {
+			case "add":
+				set.add = append(set.add, entry)
+			case "patch":
+				set.patch = append(set.patch, entry)
+			case "remove":
+				set.remove = append(set.remove, entry)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return overrideSet{}, errors.Join(errs...)
+	}
+	return set, nil
+}
+
+func decodeOverride(item *yaml.Node, fail func(int, string, ...any)) (videoOverride, bool) {
+	if item.Kind != yaml.MappingNode {
+		fail(item.Line, "entry should be a mapping of fields")
+		return videoOverride{}, false
+	}
+
+	ok := true
+	for i := 0; i < len(item.Content); i += 2 {
+		if field := item.Content[i]; !slices.Contains(overrideFields, field.Value) {
+			fail(field.Line, "unknown field [%v], expected one of %v", field.Value, overrideFields)
+			ok = false
+		}
+	}
+	if !ok {
+		return videoOverride{}, false
+	}
+
+	var entry videoOverride
+	if err := item.Decode(&entry); err != nil {
+		fail(item.Line, "%v", err)
+		return videoOverride{}, false
+	}
+	entry.line = item.Line
+	return entry, true
+}
+
+// validateOverride checks that an entry has the fields its section needs and
+// parses its duration and publish date.
+func validateOverride(section string, entry *videoOverride, fail func(int, string, ...any)) bool {
+	ok := true
+	if entry.ID == "" {
+		fail(entry.line, "entry has no [id]")
+		ok = false
+	}
+
+	set := entry.setFields()
+	switch section {
+	case "add":
+		if entry.needsLookup() && entry.URL != nil && !strings.Contains(*entry.URL, "youtube.com/") {
+			fail(entry.line, "video [%v] to add has no %v, which can only be looked up for youtube videos",
+				entry.ID, missing(requiredToAdd, set))
+			ok = false
+		}
+	case "patch":
+		if len(set) == 0 {
+			fail(entry.line, "patch for video [%v] changes no field", entry.ID)
+			ok = false
+		}
+	case "remove":
+		if len(set) > 0 {
+			fail(entry.line, "video [%v] to remove should only have an [id], not %v", entry.ID, set)
+			ok = false
+		}
+	}
+
+	if entry.Duration != nil {
+		duration, err := time.ParseDuration(*entry.Duration)
+		if err != nil || duration <= 0 {
+			fail(entry.line, "invalid duration [%v] for video [%v], expected such as 43m49s", *entry.Duration, entry.ID)
+			ok = false
+		}
+		entry.duration = duration
+	}
+	if entry.Published != nil {
+		published, err := time.Parse(time.DateOnly, *entry.Published)
+		if err != nil {
+			fail(entry.line, "invalid published date [%v] for video [%v], expected such as 2025-11-21", *entry.Published, entry.ID)
+			ok = false
+		}
+		entry.published = published
+	}
+	if entry.Language != nil {
+		if err := checkLanguageCode(*entry.Language); err != nil {
+			fail(entry.line, "%v for video [%v]", err, entry.ID)
+			ok = false
+		}
+	}
+	for _, lang := range slices.Concat(entry.AudioLanguages, entry.SubtitleLanguages) {
+		if err := checkLanguageCode(lang); err != nil {
+			fail(entry.line, "%v for video [%v]", err, entry.ID)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// needsLookup reports whether a video to add lacks fields that have to be
+// looked up on YouTube.
+func (o *videoOverride) needsLookup() bool {
+	return len(missing(requiredToAdd, o.setFields())) > 0
+}
+
+func missing(required, set []string) []string {
+	var fields []string
+	for _, field := range required {
+		if !slices.Contains(set, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// setFields lists the fields of the entry besides its id.
+func (o *videoOverride) setFields() []string {
+	var set []string
+	for field, isSet := range map[string]bool{
+		"name":        o.Name != nil,
+		"description": o.Description != nil,
+		"duration":    o.Duration != nil,
+		"language":    o.Language != nil,
+		"url":         o.URL != nil,
+		"published":   o.Published != nil,
+		"thumbnail":   o.Thumbnail != nil,
+		"audioOnly":   o.AudioOnly != nil,
+
+		"audioLanguages":    o.AudioLanguages != nil,
+		"subtitleLanguages": o.SubtitleLanguages != nil,
+	} {
+		if isSet {
+			set = append(set, field)
+		}
+	}
+	slices.Sort(set)
+	return set
+}
+
+// patchFor returns the patch of the video with the given id, or nil.
+func (s overrideSet) patchFor(id string) *videoOverride {
+	for i := range s.patch {
+		if s.patch[i].ID == id {
+			return &s.patch[i]
+		}
+	}
+	return nil
+}
+
+// applyTo sets every field the entry has on video.
+func (o *videoOverride) applyTo(video *videoMeta) {
+	video.VideoID = o.ID
+	if o.Name != nil {
+		video.Name = *o.Name
+	}
+	if o.Description != nil {
+		video.Description = *o.Description
+	}
+	if o.Duration != nil {
+		video.VideoDuration = o.duration
+	}
+	if o.Language != nil {
+		video.Language = *o.Language
+	}
+	if o.URL != nil {
+		video.ClickURL = *o.URL
+	}
+	if o.Published != nil {
+		video.PublishYear = o.published.Year()
+		video.PublishMonth = o.published.Month()
+		video.PublishDay = o.published.Day()
+	}
+	if o.Thumbnail != nil {
+		video.ThumbnailURL = *o.Thumbnail
+	}
+	if o.AudioOnly != nil {
+		video.AudioOnly = *o.AudioOnly
+	}
+	if o.AudioLanguages != nil {
+		video.AudioLanguages = o.AudioLanguages
+	}
+	if o.SubtitleLanguages != nil {
+		video.SubtitleLanguages = o.SubtitleLanguages
+	}
+}
+
 // customizeCache applies manual corrections to cached video data after it has
 // been downloaded from external sources, allowing overrides of specific fields
 // (such as language) before the cache is used elsewhere in the application.
 func customizeCache(cache *videoCache) error {
-	// Language for this video is English.
-	// UXV4hcudGo0 & 1FVPtXv2pWU videos are in english
-	toEnglish := []string{"UXV4hcudGo0", "1FVPtXv2pWU"}
-	for _, videoID := range toEnglish {
-		if video, exists := cache.get(videoID); exists {
-			video.Language = englishLang
-			cache.set(video)
-			log.Printf("Updated video %s language to %s", video.VideoID, video.Language)
-		}
-	}
-
-	// Add AajTak video
-	cache.set(videoMeta{
-		VideoID: "01vCqZoMnyE",
-		Name:    "Sahitya Aaj Tak 2025: स्वयं से साक्षात्कार | Prem Rawat | Sahitya Aaj Tak | Aaj Tak",
-		Description: "दिल्ली की गुलाबी सर्दी के बीच मेजर ध्यानचंद स्टेडियम में आजतक के बेहद चर्चित " +
-			"कार्यक्रम साहित्य आजतक 2025 का आगाज हो चुका है." +
-			"शुक्रवार को कार्यक्रम के पहले दिन न्यूयॉर्क टाइम्स के बेस्टसेलर लेखकों में शुमार प्रेम रावत ने " +
-			"आज की भागती-दौड़ती जिंदगी में शांति और आनंद का मतलब समझाया.",
-		VideoDuration: time.Minute*43 + time.Second*49,
-		Language:      hindiLang,
-		ClickURL:      "https://www.youtube.com/watch?v=01vCqZoMnyE",
-		PublishYear:   2025,
-		PublishMonth:  time.November,
-		PublishDay:    21,
-		ThumbnailURL:  "https://i.ytimg.com/vi/01vCqZoMnyE/hq720.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=_J_KLm4kj-Y
-	cache.set(videoMeta{
-		VideoID: "_J_KLm4kj-Y",
-		Name:    "ये 3 कानून ज़िंदगी बदल देंगे... Prem Rawat से जानें सत्य क्या है?. देखें पूरा वीडियो सिर्फ साहित्य तक पर.",
-		Description: "Sahitya Tak Podcast, ये 3 कानून ज़िंदगी बदल देंगे... Prem Rawat से जानें सत्य क्या है?. " +
-			"देखें पूरा वीडियो सिर्फ साहित्य तक पर.",
-		VideoDuration: time.Minute*10 + time.Second*18,
-		Language:      hindiLang,
-		ClickURL:      "https://www.youtube.com/watch?v=_J_KLm4kj-Y",
-		PublishYear:   2025,
-		PublishMonth:  time.March,
-		PublishDay:    4,
-		ThumbnailURL:  "https://i.ytimg.com/vi/_J_KLm4kj-Y/hq720.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=JW9W31HLiH0
-	cache.set(videoMeta{
-		VideoID:       "JW9W31HLiH0",
-		Name:          "जीवन! बस इन 3 नियमों पर टिका | Prem Rawat से Breath: Wake Up to Life पर बतकही | EP 109 | Sahitya Tak",
-		Description:   "",
-		VideoDuration: time.Minute*40 + time.Second*23,
-		Language:      hindiLang,
-		ClickURL:      "https://www.youtube.com/watch?v=JW9W31HLiH0",
-		PublishYear:   2025,
-		PublishMonth:  time.February,
-		PublishDay:    23,
-		ThumbnailURL:  "https://i.ytimg.com/vi/JW9W31HLiH0/hq720.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=bLzvopaMLwk
-	cache.set(videoMeta{
-		VideoID: "bLzvopaMLwk",
-		Name:    "Prem Rawat | Peace Education Keynote | Global Peace Education Day",
-		Description: `What skills and knowledge do we need to build a culture of peace on a ` +
-			`healthy planet? A keynote address from Prem Rawat, Author; Founder of ` +
-			`The Prem Rawat Foundation and the Peace Education Program.`,
-		VideoDuration: time.Minute*12 + time.Second*24,
-		Language:      englishLang,
-		ClickURL:      "https://www.youtube.com/watch?v=bLzvopaMLwk",
-		PublishYear:   2022,
-		PublishMonth:  time.October,
-		PublishDay:    22,
-		ThumbnailURL:  "https://i.ytimg.com/vi/bLzvopaMLwk/hqdefault.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=djd5THkx7Hs
-	cache.set(videoMeta{
-		VideoID:       "djd5THkx7Hs",
-		Name:          "विश्वास की बजाय अनुभव को चुनिए... पथ प्रदर्शक Prem Rawat | 'स्वयं की आवाज़' पर चर्चा | Sahitya Tak",
-		Description:   "",
-		VideoDuration: time.Minute*21 + time.Second*15,
-		Language:      hindiLang,
-		ClickURL:      "https://www.youtube.com/watch?v=djd5THkx7Hs",
-		PublishYear:   2023,
-		PublishMonth:  time.April,
-		PublishDay:    1,
-		ThumbnailURL:  "https://i.ytimg.com/vi/djd5THkx7Hs/hq720.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=tMe7_9GSXEM
-	cache.set(videoMeta{
-		VideoID:       "tMe7_9GSXEM",
-		Name:          "SPECIAL INTERVIEW WITH GLOBAL PEACE AMBASSADOR PREM RAWAT",
-		Description:   "",
-		VideoDuration: time.Minute*27 + time.Second*48,
-		Language:      hindiLang,
-		ClickURL:      "https://www.youtube.com/watch?v=tMe7_9GSXEM",
-		PublishYear:   2018,
-		PublishMonth:  time.November,
-		PublishDay:    2,
-		ThumbnailURL:  "https://i.ytimg.com/vi/tMe7_9GSXEM/hqdefault.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=zCuKz6M-hTo
-	cache.set(videoMeta{
-		VideoID:       "zCuKz6M-hTo",
-		Name:          "आखिर किसकी सुनें…दिल की या मन की ? अंतर्राष्ट्रीय वक्ता और शांति दूत Prem Rawat EXCLUSIVE | Asha Jha",
-		Description:   "",
-		VideoDuration: time.Minute*25 + time.Second*41,
-		Language:      hindiLang,
-		ClickURL:      "https://www.youtube.com/watch?v=zCuKz6M-hTo",
-		PublishYear:   2025,
-		PublishMonth:  time.April,
-		PublishDay:    13,
-		ThumbnailURL:  "https://i.ytimg.com/vi/zCuKz6M-hTo/hq720.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=-vyRZwCsn9I
-	cache.set(videoMeta{
-		VideoID:       "-vyRZwCsn9I",
-		Name:          "Jail की सज़ा काट रही इस औरत के लिए कोई Hope है? Prem Rawat ने क्या कहा | Prem Rawat Interview",
-		Description:   "",
-		VideoDuration: time.Minute*9 + time.Second*57,
-		Language:      hindiLang,
-		ClickURL:      "https://www.youtube.com/watch?v=-vyRZwCsn9I",
-		PublishYear:   2022,
-		PublishMonth:  time.March,
-		PublishDay:    9,
-		ThumbnailURL:  "https://i.ytimg.com/vi/-vyRZwCsn9I/hq720.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=4TVaZCbEpWs
-	cache.set(videoMeta{
-		VideoID:       "4TVaZCbEpWs",
-		Name:          "Prem Rawat Life Story | 4 वर्ष की उम्र में जिन्होंने रोक दी भीड़, 12 की उम्र में England को लिया लुभा",
-		Description:   "",
-		VideoDuration: time.Minute*36 + time.Second*18,
-		Language:      hindiLang,
-		ClickURL:      "https://www.youtube.com/watch?v=4TVaZCbEpWs",
-		PublishYear:   2023,
-		PublishMonth:  time.May,
-		PublishDay:    28,
-		ThumbnailURL:  "https://i.ytimg.com/vi/4TVaZCbEpWs/hq720.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=4xN4SDjbpjI
-	cache.set(videoMeta{
-		VideoID:       "4xN4SDjbpjI",
-		Name:          "लेखक , मानवतावादी 'प्रेम रावत' से खास बातचीत संजय गिरि गोस्वामी के साथ |Network10|PREM RAWAT PODCAST",
-		Description:   "",
-		VideoDuration: time.Minute*41 + time.Second*17,
-		Language:      hindiLang,
-		ClickURL:      "https://www.youtube.com/watch?v=4xN4SDjbpjI",
-		PublishYear:   2023,
-		PublishMonth:  time.July,
-		PublishDay:    25,
-		ThumbnailURL:  "https://i.ytimg.com/vi/4xN4SDjbpjI/hq720.jpg",
-		AudioOnly:     true,
-	})
-
-	// Add https://www.youtube.com/watch?v=MBnMKUE8bFo
-	cache.set(videoMeta{
-		VideoID:       "MBnMKUE8bFo",
-		Name:          "Aaj Savere - An interview with - Sh. Prem Rawat, International Peace Speaker",
-		Description:   "",
-		VideoDuration: time.Minute*48 + time.Second*55,
-		Language:      englishLang,
-		ClickURL:      "https://www.youtube.com/watch?v=MBnMKUE8bFo",
-		PublishYear:   2017,
-		PublishMonth:  time.November,
-		PublishDay:    24,
-		ThumbnailURL:  "https://i.ytimg.com/vi/MBnMKUE8bFo/hq720.jpg",
-		AudioOnly:     false,
-	})
-
-	// Add https://www.youtube.com/watch?v=k845byCwFWg
-	cache.set(videoMeta{
-		VideoID: "k845byCwFWg",
-		Name:    "Prem Rawat | Hear Yourself: How to Find Peace in a Noisy World | Talks at Google",
-		Description: `Renowned teacher and author Prem Rawat discusses his book "Hear Yourself: How to Find Peace ` +
-			`in a Noisy World", where he teaches us how to turn down the noise to “hear ourselves”—to listen to ` +
-			`the subtle song of peace that sings inside each of us. Once we learn to truly “hear ourselves” and ` +
-			`the voice of peace within, then we can hold on to that as we face all the noise of the world.\n\n` +
-			`Prem Rawat is the founder of the Prem Rawat Foundation, where he works with people from all walks of ` +
-			`life, showing them how to experience the source of peace within themselves. His work spans six decades ` +
-			`of international effort to bring a practical message of hope, happiness, and peace to all, one person ` +
-			`at a time. He is the internationally bestselling author of Peace Is Possible and is also a pilot, ` +
-			`photographer, classic car restorer, father of four children and grandfather of four.`,
-		VideoDuration: time.Minute*51 + time.Second*35,
-		Language:      englishLang,
-		ClickURL:      "https://www.youtube.com/watch?v=k845byCwFWg",
-		PublishYear:   2022,
-		PublishMonth:  time.May,
-		PublishDay:    30,
-		ThumbnailURL:  "https://i.ytimg.com/vi/k845byCwFWg/hq720.jpg",
-		AudioOnly:     false,
-	})
+	// Looking up spends YouTube quota, so it is left to updates that fetch
+	// from YouTube, others keep the videos looked up before.
+	var lookedUp map[string]videoMeta
+	if sourceEnabled(youTubeSource{}.name()) {
+		lookedUp = lookupOverrides(overrides.add)
+	}
+	for _, entry := range overrides.add {
+		video := videoMeta{}
+		if entry.needsLookup() {
+			var ok bool
+			if video, ok = lookedUp[entry.ID]; !ok {
+				if video, ok = cache.get(entry.ID); !ok {
+					log.Printf("video %s to add could not be looked up on YouTube, skipping it", entry.ID)
+					continue
+				}
+				log.Printf("video %s to add could not be looked up on YouTube, keeping the cached one", entry.ID)
+			}
+		}
+
+		video.Source = "override"
+		entry.applyTo(&video)
+		if !supportedLang(video.Language) {
+			log.Printf("video %s to add is in unsupported language %s, set its language to add it", entry.ID, video.Language)
+			continue
+		}
+		cache.set(video)
+	}
+
+	for _, entry := range overrides.patch {
+		video, exists := cache.get(entry.ID)
+		if !exists {
+			log.Printf("video %s to patch is not in the cache", entry.ID)
+			continue
+		}
+		entry.applyTo(&video)
+		cache.set(video)
+		log.Printf("Patched video %s with %v", video.VideoID, entry.setFields())
+	}
+
+	for _, entry := range overrides.remove {
+		video, exists := cache.get(entry.ID)
+		if !exists || !video.RemovedAt.IsZero() {
+			continue
+		}
+		video.RemovedAt = cache.LastUpdated
+		cache.set(video)
+		log.Printf("Removed video %s", video.VideoID)
+	}
 
 	return nil
 }
+
+// lookupOverrides looks up the videos to add that only list some of their
+// fields on YouTube, with the same batched requests used for playlist pages.
+// Videos that could not be looked up are missing from the result.
+func lookupOverrides(entries []videoOverride) map[string]videoMeta {
+	var ids []string
+	for _, entry := range entries {
+		if entry.needsLookup() {
+			ids = append(ids, entry.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	metas, err := getMetaForYouTubeVideos(ids)
+	if err != nil {
+		log.Printf("error looking up videos to add on YouTube: %v", err)
+	}
+
+	videos := make(map[string]videoMeta, len(metas))
+	for id, meta := range metas {
+		video, err := meta.toVideo(id)
+		if err != nil {
+			log.Printf("error looking up video %s to add: %v", id, err)
+			continue
+		}
+		videos[id] = video
+	}
+	return videos
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOverrides(t *testing.T) {
+	set, err := loadOverrides("")
+	assert.NoError(t, err)
+	assert.Len(t, set.add, 12)
+	assert.Len(t, set.patch, 2)
+	for _, entry := range set.add {
+		assert.False(t, entry.needsLookup(), entry.ID)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	set, err := parseOverrides("overrides.yaml", []byte(`
+add:
+  - id: abc
+    name: A talk
+    duration: 1h2m
+    language: hi-IN
+    url: https://www.youtube.com/watch?v=abc
+    published: 2025-11-21
+patch:
+  - id: def
+    audioOnly: true
+    subtitleLanguages: [hi-IN]
+remove:
+  - id: ghi
+`))
+	assert.NoError(t, err)
+
+	c := videoCache{
+		Videos: map[string]videoMeta{
+			"def": {VideoID: "def", Name: "Def"},
+			"ghi": {VideoID: "ghi"},
+		},
+		LastUpdated: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	saved := overrides
+	t.Cleanup(func() { overrides = saved })
+	overrides = set
+	assert.NoError(t, customizeCache(&c))
+
+	v, _ := c.get("abc")
+	assert.Equal(t, "A talk", v.Name)
+	assert.Equal(t, time.Hour+2*time.Minute, v.VideoDuration)
+	assert.Equal(t, time.November, v.PublishMonth)
+
+	v, _ = c.get("def")
+	assert.Equal(t, "Def", v.Name)
+	assert.True(t, v.AudioOnly)
+	assert.Equal(t, []string{hindiLang}, v.SubtitleLanguages)
+
+	v, _ = c.get("ghi")
+	assert.Equal(t, c.LastUpdated, v.RemovedAt)
+}
+
+func TestInvalidOverrides(t *testing.T) {
+	_, err := parseOverrides("overrides.yaml", []byte(`
+add:
+  - id: abc
+    name: A talk
+    duration: long
+    url: https://open.spotify.com/episode/abc
+patch:
+  - id: def
+  - id: ghi
+    colour: red
+remove:
+  - id: jkl
+    name: J
+`))
+	assert.EqualError(t, err, `overrides.yaml:3: video [abc] to add has no [language published], which can only be looked up for youtube videos
+overrides.yaml:3: invalid duration [long] for video [abc], expected such as 43m49s
+overrides.yaml:8: patch for video [def] changes no field
+overrides.yaml:10: unknown field [colour], expected one of [id name description duration language url published thumbnail audioOnly audioLanguages subtitleLanguages]
+overrides.yaml:12: video [jkl] to remove should only have an [id], not [name]`)
+}
+
+func TestExclusions(t *testing.T) {
+	set, err := parseOverrides("overrides.yaml", []byte(`
+exclude:
+  - title: (?i)\btrailer\b
+    shorterThan: 3m
+    reason: trailers
+  - channel: "@wopgyt"
+    id: dup
+`))
+	assert.NoError(t, err)
+
+	c := videoCache{Videos: map[string]videoMeta{
+		"short":   {VideoID: "short", Name: "Official Trailer", VideoDuration: time.Minute},
+		"unknown": {VideoID: "unknown", Name: "Trailer"},
+		"long":    {VideoID: "long", Name: "Trailer talk", VideoDuration: time.Hour},
+		"dup":     {VideoID: "dup", Channel: "@WOPGYT"},
+		"dup-ok":  {VideoID: "dup-ok", Channel: "@wopgyt"},
+	}}
+	counts := excludeVideos(&c, set.exclude)
+	assert.Equal(t, map[string]int{
+		`line 3: title (?i)\btrailer\b, shorter than 3m (trailers)`: 1,
+		"line 6: id dup, channel @wopgyt":                           1,
+	}, counts)
+	assert.NotEmpty(t, c.Videos["short"].Excluded)
+	assert.Empty(t, c.Videos["long"].Excluded)
+	assert.Empty(t, c.Videos["unknown"].Excluded, "unknown duration is not short")
+	assert.NotEmpty(t, c.Videos["dup"].Excluded)
+	assert.Empty(t, c.Videos["dup-ok"].Excluded)
+
+	_, err = parseOverrides("overrides.yaml", []byte(`
+exclude:
+  - reason: nothing to match
+  - title: "("
+`))
+	assert.EqualError(t, err, "overrides.yaml:3: exclusion needs at least one of [id], [title], [channel] or [shorterThan]\n"+
+		"overrides.yaml:4: invalid title pattern [(]: error parsing regexp: missing closing ): `(`")
+}
+
+func TestPatchedFieldsAreNotChanges(t *testing.T) {
+	set, err := parseOverrides("overrides.yaml", []byte(`
+patch:
+  - id: abc
+    name: Corrected title
+`))
+	assert.NoError(t, err)
+	saved, savedChanged := overrides, revalidateChanged
+	t.Cleanup(func() { overrides, revalidateChanged = saved, savedChanged })
+	overrides, revalidateChanged = set, true
+
+	now := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	c := videoCache{Videos: map[string]videoMeta{
+		"abc": {VideoID: "abc", Name: "Corrected title", Description: "About peace", Source: "youtube", LastSeen: now},
+	}}
+
+	var item playlistItem
+	item.Snippet.ResourceID.VideoID = "abc"
+	item.Snippet.Title = "Wrong title"
+	item.Snippet.Description = "About peace"
+	assert.False(t, needsRevalidation(c.Videos["abc"], item))
+	item.Snippet.Description = "About joy"
+	assert.True(t, needsRevalidation(c.Videos["abc"], item))
+
+	_, updated, _ := c.merge("youtube", []videoMeta{{VideoID: "abc", Name: "Wrong title", Description: "About joy"}}, now, false)
+	assert.Equal(t, 1, updated)
+	assert.Equal(t, []videoChange{{VideoID: "abc", Field: "Description", Old: "About peace", New: "About joy"}}, c.Changes)
+}
+
+func TestLookupOverrides(t *testing.T) {
+	set, err := parseOverrides("overrides.yaml", []byte(`
+add:
+  - id: abc
+    language: hi-IN
+  - id: gone
+`))
+	assert.NoError(t, err)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "abc,gone", r.URL.Query().Get("id"))
+		fmt.Fprint(w, `{"items": [{"id": "abc", "snippet": {"title": "Inner peace", "publishedAt": "2025-11-21T10:00:00Z",
+			"defaultAudioLanguage": "en", "thumbnails": {"medium": {"url": "https://i.ytimg.com/abc.jpg"}}},
+			"contentDetails": {"duration": "PT43M49S"}}]}`)
+	}))
+	defer server.Close()
+
+	savedURL, savedOverrides, savedSources := videoMetaURL, overrides, enabledSources
+	t.Cleanup(func() { videoMetaURL, overrides, enabledSources = savedURL, savedOverrides, savedSources })
+	videoMetaURL = server.URL + "/videos?id=%v&key=%v"
+	overrides = set
+
+	enabledSources = []contentSource{ttSource{}}
+	c := videoCache{Videos: map[string]videoMeta{}}
+	assert.NoError(t, customizeCache(&c))
+	assert.Equal(t, 0, requests, "no quota spent without youtube")
+	assert.Empty(t, c.Videos)
+
+	enabledSources = allSources
+	assert.NoError(t, customizeCache(&c))
+	assert.Equal(t, 1, requests)
+	assert.Len(t, c.Videos, 1)
+	v, _ := c.get("abc")
+	assert.Equal(t, "Inner peace", v.Name)
+	assert.Equal(t, hindiLang, v.Language, "the override wins over youtube")
+	assert.Equal(t, 43*time.Minute+49*time.Second, v.VideoDuration)
+	assert.Equal(t, "override", v.Source)
+	assert.Equal(t, time.November, v.PublishMonth)
+}
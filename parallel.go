@@ -0,0 +1,30 @@
+package main
+
+import "sync"
+
+// fetchWorkers bounds how many fetches each stage of a refresh, such as the
+// YouTube handles or the TT languages, runs at the same time.
+var fetchWorkers = 4
+
+// forEach calls fn for every index in [0, n) using at most fetchWorkers
+// goroutines and returns once all calls are done. Callers keep the output
+// deterministic by writing results into slots indexed by i.
+func forEach(n int, fn func(i int)) {
+	workers := max(1, min(fetchWorkers, n))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range next {
+				fn(i)
+			}
+		})
+	}
+
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
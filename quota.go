@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// youTubeCallCost is the quota cost in units of each YouTube Data API call a
+// refresh makes, see https://developers.google.com/youtube/v3/determine_quota_cost
+var youTubeCallCost = map[string]int64{
+	"channels":      1,
+	"playlistItems": 1,
+	"videos":        1,
+}
+
+// errQuotaExhausted is returned once a call would exceed the quota budget of
+// the run. Videos fetched before that are still returned along with it.
+var errQuotaExhausted = errors.New("youtube quota budget reached")
+
+// youTubeQuota keeps track of the quota units used by the current run.
+var youTubeQuota quota
+
+type quota struct {
+	mu     sync.Mutex
+	used   int64
+	budget int64
+}
+
+// reset starts counting from zero with the given budget, zero means unlimited.
+func (q *quota) reset(budget int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.used = 0
+	q.budget = budget
+}
+
+// spend accounts for a call before it is made and returns errQuotaExhausted
+// instead when the call would go over the budget.
+func (q *quota) spend(call string) error {
+	cost, ok := youTubeCallCost[call]
+	if !ok {
+		panic(fmt.Sprintf("no quota cost known for youtube call [%v]", call))
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.budget > 0 && q.used+cost > q.budget {
+		return fmt.Errorf("%w: [%v] of [%v] units used", errQuotaExhausted, q.used, q.budget)
+	}
+	q.used += cost
+	return nil
+}
+
+func (q *quota) total() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.used
+}
+
+func (q *quota) logUsage(context string) {
+	log.Printf("youtube quota used after %v: [%v] units\n", context, q.total())
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// exitPartialRefresh is the exit status of a refresh that saved the cache but
+// could not fetch every source.
+const exitPartialRefresh = 3
+
+// errNothingFetched is returned by a refresh in which every source failed. The
+// cache is then left as it was, with its LastUpdated unchanged.
+var errNothingFetched = errors.New("no source could be fetched")
+
+// fetchFailure is a part of a source, such as a single YouTube handle or TT
+// language, that could not be fetched.
+type fetchFailure struct {
+	source string
+	part   string
+	err    error
+}
+
+func (f fetchFailure) String() string {
+	if f.part == "" {
+		return fmt.Sprintf("[%v]: %v", f.source, f.err)
+	}
+	return fmt.Sprintf("[%v/%v]: %v", f.source, f.part, f.err)
+}
+
+// partialError is returned by a source that could fetch only some of its
+// parts. The videos returned along with it are still merged into the cache,
+// but nothing is marked removed as the failed parts may still list them.
+type partialError struct {
+	failures []fetchFailure
+}
+
+func (e *partialError) add(part string, err error) {
+	e.failures = append(e.failures, fetchFailure{part: part, err: err})
+}
+
+// orNil returns nil when nothing failed so callers can return it directly.
+func (e *partialError) orNil() error {
+	if len(e.failures) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *partialError) Error() string {
+	parts := make([]string, 0, len(e.failures))
+	for _, f := range e.failures {
+		parts = append(parts, fmt.Sprintf("[%v]: %v", f.part, f.err))
+	}
+	return fmt.Sprintf("failed to fetch %v part(s): %v", len(e.failures), strings.Join(parts, "; "))
+}
+
+// refreshError is returned by a refresh that saved the cache with the sources
+// that succeeded while others, or parts of them, failed.
+type refreshError struct {
+	failures []fetchFailure
+}
+
+func (e *refreshError) Error() string {
+	return fmt.Sprintf("refresh failed for %v source part(s)", len(e.failures))
+}
+
+// summary lists every failure on its own line.
+func (e *refreshError) summary() string {
+	lines := make([]string, 0, len(e.failures))
+	for _, f := range e.failures {
+		lines = append(lines, "  "+f.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// nuktaLetters maps the Devanagari letters written with a nukta to the letter
+// without it, as Hindi is often typed either way.
+var nuktaLetters = map[rune]rune{
+	'क़': 'क', 'ख़': 'ख', 'ग़': 'ग', 'ज़': 'ज',
+	'ड़': 'ड', 'ढ़': 'ढ', 'फ़': 'फ', 'य़': 'य',
+	'ऩ': 'न', 'ऱ': 'र', 'ऴ': 'ळ',
+}
+
+// foldText lowercases text and folds the Devanagari spelling variants
+// that read the same: letters with and without a nukta, and chandrabindu and
+// anusvara. Zero width joiners, which only change how text is drawn, are
+// dropped.
+func foldText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if base, ok := nuktaLetters[r]; ok {
+			return base
+		}
+		switch r {
+		case '़', '‌', '‍':
+			return -1
+		case 'ँ':
+			return 'ं'
+		}
+		return unicode.ToLower(r)
+	}, text)
+}
+
+// tokenize splits text into folded words, leaving out punctuation.
+func tokenize(text string) []string {
+	spans := wordSpans(text)
+	words := make([]string, len(spans))
+	for i, s := range spans {
+		words[i] = s.word
+	}
+	return words
+}
+
+// wordSpan is a word of a text, folded, with where it is in the text.
+type wordSpan struct {
+	word       string
+	start, end int
+}
+
+// wordSpans splits text into words at anything but letters, digits, marks and
+// zero width joiners. Vowel signs are marks and joiners shape conjuncts, so
+// Devanagari words stay whole.
+func wordSpans(text string) []wordSpan {
+	var spans []wordSpan
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '\u200c' || r == '\u200d'
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			spans = append(spans, wordSpan{word: foldText(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, wordSpan{word: foldText(text[start:]), start: start, end: len(text)})
+	}
+	return slices.DeleteFunc(spans, func(s wordSpan) bool { return s.word == "" })
+}
+
+// searchDoc is the tokenized text of a video that queries are matched with,
+// along with the phonetic key of every word.
+type searchDoc struct {
+	title     []string
+	desc      []string
+	titleKeys []string
+	descKeys  []string
+}
+
+func newSearchDoc(video videoMeta) searchDoc {
+	title, desc := tokenize(video.Name), tokenize(video.Description)
+	return searchDoc{title: title, desc: desc, titleKeys: phoneticKeys(title), descKeys: phoneticKeys(desc)}
+}
+
+func phoneticKeys(words []string) []string {
+	keys := make([]string, len(words))
+	for i, word := range words {
+		keys[i] = phoneticKey(word)
+	}
+	return keys
+}
+
+// queryNode is a parsed query, or a part of one.
+type queryNode interface {
+	match(doc searchDoc) bool
+}
+
+type (
+	andNode []queryNode
+	orNode  []queryNode
+	notNode struct{ node queryNode }
+	// termNode matches a word, or a phrase of words next to each other, in
+	// the title, the description or either. A single word term also matches
+	// words it starts, so breath matches breathing. Words also match words
+	// of the other script with the same phonetic key, so shanti matches
+	// शांति.
+	termNode struct {
+		field  string
+		words  []string
+		keys   []string
+		phrase bool
+	}
+)
+
+func (n andNode) match(doc searchDoc) bool {
+	for _, node := range n {
+		if !node.match(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n orNode) match(doc searchDoc) bool {
+	for _, node := range n {
+		if node.match(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n notNode) match(doc searchDoc) bool {
+	return !n.node.match(doc)
+}
+
+func (n termNode) match(doc searchDoc) bool {
+	switch n.field {
+	case "title":
+		return n.matchWords(doc.title, doc.titleKeys)
+	case "desc":
+		return n.matchWords(doc.desc, doc.descKeys)
+	default:
+		return n.matchWords(doc.title, doc.titleKeys) || n.matchWords(doc.desc, doc.descKeys)
+	}
+}
+
+func (n termNode) matchWords(words, keys []string) bool {
+	for start := 0; start+len(n.words) <= len(words); start++ {
+		matched := true
+		for i := range n.words {
+			if !n.wordMatches(i, words[start+i], keys[start+i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// wordMatches reports whether a word of a video, with its phonetic key,
+// matches the i-th word of the term.
+func (n termNode) wordMatches(i int, word, key string) bool {
+	if word == n.words[i] || !n.phrase && strings.HasPrefix(word, n.words[i]) {
+		return true
+	}
+	return key != "" && key == n.keys[i] && acrossScripts(word, n.words[i])
+}
+
+// queryFields are the fields a term can be scoped to, as in title:breath.
+var queryFields = []string{"title", "desc"}
+
+// parseQuery parses a search query such as
+//
+//	title:"inner peace" (breath OR श्वास) -trailer
+//
+// Terms next to each other must all match, unless joined by OR. AND, OR and
+// NOT are only operators when written in capitals, and -term is NOT term.
+// An empty query is nil.
+func parseQuery(query string) (queryNode, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	p := queryParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid query [%v]: %w", query, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid query [%v]: unexpected [%v]", query, p.tokens[p.pos].text)
+	}
+	return node, nil
+}
+
+type queryToken struct {
+	text   string
+	field  string
+	quoted bool
+	// op is set for parentheses, - and the AND, OR and NOT operators.
+	op bool
+}
+
+func lexQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(' || r == ')':
+			tokens = append(tokens, queryToken{text: string(r), op: true})
+			i++
+			continue
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, queryToken{text: "-", op: true})
+			i++
+			continue
+		}
+
+		var token queryToken
+		start := i
+		for i < len(runes) && runes[i] != ':' && runes[i] != '"' && !isQueryBreak(runes[i]) {
+			i++
+		}
+		// Only the known fields scope a term, others such as 10:30 are
+		// searched as they are.
+		if i < len(runes) && runes[i] == ':' && slices.Contains(queryFields, string(runes[start:i])) {
+			token.field = string(runes[start:i])
+			i++
+			start = i
+		}
+
+		if i == start && i < len(runes) && runes[i] == '"' {
+			end := slices.Index(runes[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in query [%v]", query)
+			}
+			token.text = string(runes[i+1 : i+1+end])
+			token.quoted = true
+			i += end + 2
+		} else {
+			for i < len(runes) && !isQueryBreak(runes[i]) {
+				i++
+			}
+			token.text = string(runes[start:i])
+			token.op = token.field == "" && slices.Contains([]string{"AND", "OR", "NOT"}, token.text)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func isQueryBreak(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')'
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peekOp(ops ...string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].op && slices.Contains(ops, p.tokens[p.pos].text)
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	var nodes orNode
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if !p.peekOp("OR") {
+			break
+		}
+		p.pos++
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	var nodes andNode
+	for p.pos < len(p.tokens) && !p.peekOp("OR", ")") {
+		if p.peekOp("AND") {
+			p.pos++
+			continue
+		}
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	switch len(nodes) {
+	case 0:
+		return nil, fmt.Errorf("expected a term")
+	case 1:
+		return nodes[0], nil
+	default:
+		return nodes, nil
+	}
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.peekOp("NOT", "-") {
+		p.pos++
+		if p.pos == len(p.tokens) || p.peekOp("OR", "AND", ")") {
+			return nil, fmt.Errorf("expected a term after NOT")
+		}
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	token := p.tokens[p.pos]
+	p.pos++
+	if token.op && token.text == "(" {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekOp(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return node, nil
+	}
+	if token.op {
+		return nil, fmt.Errorf("unexpected [%v]", token.text)
+	}
+
+	words := tokenize(token.text)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("nothing to search for in [%v]", token.text)
+	}
+	return termNode{field: token.field, words: words, keys: phoneticKeys(words), phrase: token.quoted || len(words) > 1}, nil
+}
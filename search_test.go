@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizeJoiners(t *testing.T) {
+	// Zero width joiners shape conjuncts inside a word and are folded away.
+	assert.Equal(t, []string{"क्षमा", "है"}, tokenize("क्\u200dषमा है"))
+	assert.Equal(t, []string{"क्षमा"}, tokenize("क्\u200cषमा"))
+	assert.Equal(t, []string{"peace"}, tokenize("\u200dpeace\u200c"))
+}
+
+func TestParseQuery(t *testing.T) {
+	videos := []videoMeta{
+		{VideoID: "breath", Name: "Breathing and inner peace", Description: "A talk about breath."},
+		{VideoID: "peace", Name: "Peace Is Possible", Description: "Inner peace at the conference."},
+		{VideoID: "hindi", Name: "शांति की खोज", Description: "ज़िंदगी में आनंद"},
+		{VideoID: "trailer", Name: "Inner peace trailer", Description: "Coming soon, at 10:30."},
+	}
+	search := func(query string) []string {
+		node, err := parseQuery(query)
+		assert.NoError(t, err, query)
+		var ids []string
+		for _, video := range videos {
+			if node.match(newSearchDoc(video)) {
+				ids = append(ids, video.VideoID)
+			}
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"breath"}, search("breath"))
+	assert.Equal(t, []string{"breath", "peace", "trailer"}, search(`"inner peace"`))
+	assert.Equal(t, []string{"peace"}, search(`desc:"inner peace"`))
+	assert.Equal(t, []string{"breath", "trailer"}, search(`title:"inner peace"`))
+	assert.Equal(t, []string{"breath", "peace"}, search(`"inner peace" -trailer`))
+	assert.Equal(t, []string{"breath", "peace"}, search(`"inner peace" AND NOT trailer`))
+	assert.Equal(t, []string{"breath", "hindi"}, search("breath OR शांति"))
+	assert.Equal(t, []string{"hindi"}, search("(trailer OR शाँति) जिंदगी"))
+	assert.Equal(t, []string{"breath"}, search("peace and breathing"))
+	assert.Equal(t, []string{"trailer"}, search("10:30"))
+	assert.Empty(t, search("author:x"))
+
+	node, err := parseQuery("  ")
+	assert.NoError(t, err)
+	assert.Nil(t, node)
+
+	for query, msg := range map[string]string{
+		`"peace`:      `unterminated quote in query ["peace]`,
+		"(peace":      "invalid query [(peace]: missing )",
+		"peace OR":    "invalid query [peace OR]: expected a term",
+		"peace NOT":   "invalid query [peace NOT]: expected a term after NOT",
+		"peace) joy":  "invalid query [peace) joy]: unexpected [)]",
+		"title:\"!\"": `invalid query [title:"!"]: nothing to search for in [!]`,
+	} {
+		_, err := parseQuery(query)
+		assert.EqualError(t, err, msg, query)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// videoSorts are the values of the sort parameter of /videos. relevance is
+// the default when searching with q, newest otherwise.
+var videoSorts = []string{"newest", "oldest", "longest", "shortest", "name", "relevance"}
+
+// cacheServer serves the cache file over HTTP, reading it again whenever it
+// changes on disk.
+type cacheServer struct {
+	mu      sync.RWMutex
+	cache   *videoCache
+	index   *searchIndex
+	modTime time.Time
+}
+
+func runServe(fs *flag.FlagSet, args []string) error {
+	var settings settingsFlags
+	settings.register(fs)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	if err := parseCommand(fs, args, false); err != nil {
+		return err
+	}
+	if err := settings.apply(); err != nil {
+		return err
+	}
+
+	s := &cacheServer{}
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("serving [%v] on [%v]\n", cacheFile, *addr)
+	return server.ListenAndServe()
+}
+
+func (s *cacheServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /stats", s.handleStats)
+	mux.HandleFunc("GET /videos", s.handleVideos)
+	mux.HandleFunc("GET /videos/{id}", s.handleVideo)
+	return mux
+}
+
+// reload reads the cache file again if it changed since it was last read.
+// When that fails the cache read before is kept. The file is read and indexed
+// without holding the lock, so requests are served from the cache read
+// before in the meantime.
+func (s *cacheServer) reload() error {
+	info, err := os.Stat(cacheFile)
+	if err != nil {
+		return fmt.Errorf("error checking cache file [%v]: %w", cacheFile, err)
+	}
+
+	s.mu.RLock()
+	unchanged := s.cache != nil && info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	c := &videoCache{}
+	if err := c.read(); err != nil {
+		return err
+	}
+	idx, err := refreshIndex(c.Videos, false)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache != nil && info.ModTime().Equal(s.modTime) {
+		// Another request loaded it first.
+		return nil
+	}
+	s.cache, s.index, s.modTime = c, idx, info.ModTime()
+	log.Printf("loaded [%v] videos from [%v]\n", len(c.Videos), cacheFile)
+	return nil
+}
+
+// current returns the cache and its index after reading the cache file again
+// if it changed. The cache returned is never modified, a reload replaces it.
+func (s *cacheServer) current() (*videoCache, *searchIndex, error) {
+	if err := s.reload(); err != nil {
+		log.Printf("error reloading cache, serving the last one read: %v\n", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cache == nil {
+		return nil, nil, errors.New("no cache loaded")
+	}
+	return s.cache, s.index, nil
+}
+
+func (s *cacheServer) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	c, _, err := s.current()
+	if err != nil {
+		writeJSONResponse(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+
+	s.mu.RLock()
+	modTime := s.modTime
+	s.mu.RUnlock()
+	writeJSONResponse(w, http.StatusOK, map[string]any{
+		"status":        "ok",
+		"videos":        len(c.Videos),
+		"lastUpdated":   c.LastUpdated,
+		"cacheModified": modTime,
+	})
+}
+
+func (s *cacheServer) handleStats(w http.ResponseWriter, _ *http.Request) {
+	c, _, err := s.current()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, computeStats(c))
+}
+
+func (s *cacheServer) handleVideo(w http.ResponseWriter, r *http.Request) {
+	c, _, err := s.current()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	// Removed and excluded videos are not served, as /videos never lists
+	// them.
+	video, ok := c.get(r.PathValue("id"))
+	if !ok || !searchable(video) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no video [%v]", r.PathValue("id")))
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, newResultRow(video))
+}
+
+// videoPage is a page of the videos matching the filters of /videos.
+type videoPage struct {
+	Total    int         `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"pageSize"`
+	Videos   []resultRow `json:"videos"`
+}
+
+// handleVideos lists the videos matching the filters given as query
+// parameters named like the command line flags, such as
+// /videos?lang=hi-IN&maxDuration=30m&q=peace&sort=newest&page=2.
+func (s *cacheServer) handleVideos(w http.ResponseWriter, r *http.Request) {
+	c, idx, err := s.current()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	fs := flag.NewFlagSet("videos", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var filters filterFlags
+	filters.register(fs)
+
+	query := r.URL.Query()
+	for key, values := range query {
+		if key == "page" || key == "pageSize" || key == "sort" {
+			continue
+		}
+		if fs.Lookup(key) == nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown parameter [%v]", key))
+			return
+		}
+		if err := fs.Set(key, values[len(values)-1]); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid parameter [%v]: %w", key, err))
+			return
+		}
+	}
+
+	page, err := intParam(query.Get("page"), 1)
+	if err != nil || page < 1 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid page [%v], expected a number from 1", query.Get("page")))
+		return
+	}
+	pageSize, err := intParam(query.Get("pageSize"), defaultPageSize)
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid pageSize [%v], expected a number from 1 to %v", query.Get("pageSize"), maxPageSize))
+		return
+	}
+	order := query.Get("sort")
+	if order != "" && !slices.Contains(videoSorts, order) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown sort [%v], expected one of %v", order, videoSorts))
+		return
+	}
+
+	params, err := filters.params(time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	filtered, err := filterContent(c.Videos, params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var rows []resultRow
+	if params.query != "" && (order == "" || order == "relevance") {
+		node, err := parseQuery(params.query)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		for _, hit := range idx.rank(filtered, node) {
+			row := newResultRow(hit.video)
+			row.Score, row.Snippet, row.ranked = hit.score, hit.snippet, true
+			rows = append(rows, row)
+		}
+	} else {
+		sortVideos(filtered, order)
+		for _, video := range filtered {
+			rows = append(rows, newResultRow(video))
+		}
+	}
+
+	start := min((page-1)*pageSize, len(rows))
+	end := min(start+pageSize, len(rows))
+	writeJSONResponse(w, http.StatusOK, videoPage{
+		Total:    len(rows),
+		Page:     page,
+		PageSize: pageSize,
+		Videos:   append([]resultRow{}, rows[start:end]...),
+	})
+}
+
+// sortVideos sorts videos in one of videoSorts, newest first by default,
+// falling back to the VideoID so that pages do not overlap.
+func sortVideos(videos []videoMeta, order string) {
+	slices.SortFunc(videos, func(a, b videoMeta) int {
+		var n int
+		switch order {
+		case "oldest":
+			n = publishDate(a).Compare(publishDate(b))
+		case "longest":
+			n = cmp.Compare(b.VideoDuration, a.VideoDuration)
+		case "shortest":
+			n = cmp.Compare(a.VideoDuration, b.VideoDuration)
+		case "name":
+			n = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		default:
+			n = publishDate(b).Compare(publishDate(a))
+		}
+		if n != 0 {
+			return n
+		}
+		return strings.Compare(a.VideoID, b.VideoID)
+	})
+}
+
+func intParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSONResponse(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error writing response: %v\n", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	t.Chdir(t.TempDir())
+	c := videoCache{Videos: map[string]videoMeta{
+		"a": {VideoID: "a", Name: "Peace", Language: hindiLang, Source: "tt", VideoDuration: 20 * time.Minute,
+			PublishYear: 2025, PublishMonth: time.March, PublishDay: 1},
+		"b": {VideoID: "b", Name: "Breath", Language: hindiLang, Source: "youtube", VideoDuration: time.Hour,
+			PublishYear: 2025, PublishMonth: time.April, PublishDay: 1},
+		"c": {VideoID: "c", Name: "Joy", Language: englishLang, Source: "youtube", VideoDuration: 10 * time.Minute,
+			PublishYear: 2024, PublishMonth: time.May, PublishDay: 1},
+
+		"gone":    {VideoID: "gone", Name: "Gone", RemovedAt: time.Now()},
+		"trailer": {VideoID: "trailer", Name: "Trailer", Excluded: "trailers"},
+	}}
+	assert.NoError(t, c.save())
+
+	s := &cacheServer{}
+	assert.NoError(t, s.reload())
+	handler := s.routes()
+	get := func(url string, v any) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), v), url)
+		return rec.Code
+	}
+	ids := func(page videoPage) []string {
+		var ids []string
+		for _, row := range page.Videos {
+			ids = append(ids, row.VideoID)
+		}
+		return ids
+	}
+
+	var page videoPage
+	assert.Equal(t, http.StatusOK, get("/videos?lang=hi&sort=shortest", &page))
+	assert.Equal(t, []string{"a", "b"}, ids(page))
+	assert.Equal(t, http.StatusOK, get("/videos?pageSize=2&page=2", &page))
+	assert.Equal(t, 3, page.Total)
+	assert.Equal(t, []string{"c"}, ids(page))
+	assert.Equal(t, http.StatusOK, get("/videos?q=breath", &page))
+	assert.Equal(t, []string{"b"}, ids(page))
+
+	var failure map[string]string
+	assert.Equal(t, http.StatusBadRequest, get("/videos?colour=red", &failure))
+	assert.Equal(t, "unknown parameter [colour]", failure["error"])
+	assert.Equal(t, http.StatusBadRequest, get("/videos?maxDuration=long", &failure))
+	assert.Equal(t, http.StatusNotFound, get("/videos/zzz", &failure))
+	assert.Equal(t, http.StatusNotFound, get("/videos/gone", &failure))
+	assert.Equal(t, http.StatusNotFound, get("/videos/trailer", &failure))
+
+	var row resultRow
+	assert.Equal(t, http.StatusOK, get("/videos/c", &row))
+	assert.Equal(t, "Joy", row.Name)
+
+	var stats cacheStats
+	assert.Equal(t, http.StatusOK, get("/stats", &stats))
+	assert.Equal(t, 3, stats.Videos)
+	assert.Equal(t, map[string]int{"2024": 1, "2025": 2}, stats.ByYear)
+
+	// A new cache file is picked up by the next request.
+	delete(c.Videos, "c")
+	assert.NoError(t, c.save())
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(cacheFile, later, later))
+	assert.Equal(t, http.StatusOK, get("/stats", &stats))
+	assert.Equal(t, 2, stats.Videos)
+}
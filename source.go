@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// capability describes what kind of content a source provides and what it
+// needs in order to fetch it.
+type capability uint
+
+const (
+	// capVideo marks sources that serve video content.
+	capVideo capability = 1 << iota
+	// capAudio marks sources that serve audio-only content.
+	capAudio
+	// capRemote marks sources that have to reach a remote API while fetching.
+	capRemote
+)
+
+func (c capability) has(other capability) bool {
+	return c&other == other
+}
+
+func (c capability) String() string {
+	var names []string
+	if c.has(capVideo) {
+		names = append(names, "video")
+	}
+	if c.has(capAudio) {
+		names = append(names, "audio")
+	}
+	if c.has(capRemote) {
+		names = append(names, "remote")
+	}
+	return strings.Join(names, ",")
+}
+
+// contentSource is a platform that Prem Rawat's content is fetched from.
+// Adding a new platform only needs a new implementation registered in
+// allSources, the cache iterates whatever sources are enabled.
+type contentSource interface {
+	name() string
+	fetch() ([]videoMeta, error)
+	capabilities() capability
+}
+
+// incrementalSource is implemented by sources that may list only their newest
+// videos. Cached videos they do not list are then kept as they are instead of
+// being marked removed.
+type incrementalSource interface {
+	incremental() bool
+}
+
+var (
+	allSources     = []contentSource{ttSource{}, youTubeSource{}, spotifySource{}}
+	enabledSources = allSources
+)
+
+// sourceEnabled reports whether the named source is fetched on update.
+func sourceEnabled(name string) bool {
+	return slices.ContainsFunc(enabledSources, func(src contentSource) bool { return src.name() == name })
+}
+
+type ttSource struct{}
+
+func (ttSource) name() string                { return "tt" }
+func (ttSource) fetch() ([]videoMeta, error) { return getTTContent() }
+func (ttSource) capabilities() capability    { return capVideo | capAudio | capRemote }
+
+type youTubeSource struct{}
+
+func (youTubeSource) name() string             { return "youtube" }
+func (youTubeSource) capabilities() capability { return capVideo | capRemote }
+
+func (s youTubeSource) fetch() ([]videoMeta, error) {
+	return getYouTubeContent(s.incremental())
+}
+
+// incremental is true unless the last full sync is older than
+// youTubeFullSyncInterval, so removed videos are still noticed now and then.
+func (s youTubeSource) incremental() bool {
+	lastFullSync := cache.FullSyncs[s.name()]
+	return youTubeFullSyncInterval > 0 && time.Since(lastFullSync) < youTubeFullSyncInterval
+}
+
+type spotifySource struct{}
+
+func (spotifySource) name() string                { return "spotify" }
+func (spotifySource) fetch() ([]videoMeta, error) { return getSpotifyContent() }
+func (spotifySource) capabilities() capability    { return capAudio }
+
+func sourceNames(sources []contentSource) []string {
+	names := make([]string, 0, len(sources))
+	for _, src := range sources {
+		names = append(names, src.name())
+	}
+	return names
+}
+
+// selectSources parses a comma separated list of source names. Names prefixed
+// with "-" are disabled, and when only such names are given the remaining
+// registered sources stay enabled.
+func selectSources(spec string) ([]contentSource, error) {
+	if strings.TrimSpace(spec) == "" {
+		return allSources, nil
+	}
+
+	enable := make(map[string]bool)
+	disable := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		skip := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		if findSource(name) == nil {
+			return nil, fmt.Errorf("unknown source [%v], known sources are %v", name, sourceNames(allSources))
+		}
+
+		if skip {
+			disable[name] = true
+		} else {
+			enable[name] = true
+		}
+	}
+
+	var selected []contentSource
+	for _, src := range allSources {
+		if disable[src.name()] {
+			continue
+		}
+		if len(enable) > 0 && !enable[src.name()] {
+			continue
+		}
+		selected = append(selected, src)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no source enabled by [%v]", spec)
+	}
+
+	return selected, nil
+}
+
+func findSource(name string) contentSource {
+	for _, src := range allSources {
+		if src.name() == name {
+			return src
+		}
+	}
+	return nil
+}
@@ -16,26 +16,32 @@ var spotifyHTML []byte
 var sentenceBoundary = regexp.MustCompile(`([.!?])([A-Z])`)
 var spotifyPublishDate = time.Date(2026, time.January, 27, 0, 0, 0, 0, time.UTC)
 
-// customizeSpotifyCache adds Spotify podcast episodes to the cache
-func customizeSpotifyCache(cache *videoCache) error {
+// getSpotifyContent returns the podcast episodes listed in the embedded
+// Spotify show page.
+func getSpotifyContent() ([]videoMeta, error) {
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(spotifyHTML))
 	if err != nil {
-		return fmt.Errorf("error parsing data/spotify.html: %w", err)
+		return nil, fmt.Errorf("error parsing data/spotify.html: %w", err)
 	}
 
-	doc.Find(`[data-testid^="episode-"]`).Each(func(_ int, s *goquery.Selection) {
+	var videos []videoMeta
+	var parseErr error
+	doc.Find(`[data-testid^="episode-"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
 		vm, err := parseEpisode(s)
 		if err != nil {
-			panic(err)
+			parseErr = err
+			return false
 		}
-		if vm == nil {
-			return
+		if vm != nil {
+			videos = append(videos, *vm)
 		}
-
-		cache.set(*vm)
+		return true
 	})
+	if parseErr != nil {
+		return nil, fmt.Errorf("error parsing spotify episode: %w", parseErr)
+	}
 
-	return nil
+	return videos, nil
 }
 
 func parseEpisode(s *goquery.Selection) (*videoMeta, error) {
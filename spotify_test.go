@@ -8,11 +8,16 @@ import (
 )
 
 func TestSpotify(t *testing.T) {
-	cache = videoCache{Videos: make(map[string]videoMeta)}
-	if err := customizeSpotifyCache(&cache); err != nil {
+	videos, err := getSpotifyContent()
+	if err != nil {
 		t.Fatal(err)
 	}
 
+	cache = videoCache{Videos: make(map[string]videoMeta)}
+	for _, video := range videos {
+		cache.set(video)
+	}
+
 	v, ok := cache.get("5PSCnndWS27XzNv43djH0g")
 	assert.True(t, ok)
 	assert.Equal(t, v.Name, "Tired of your hidden load?")
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// cacheStats sums up the cache. The counts by source, language and year are
+// of the videos that are neither removed nor excluded.
+type cacheStats struct {
+	Videos           int            `json:"videos"`
+	Talks            int            `json:"talks"`
+	Removed          int            `json:"removed"`
+	Excluded         int            `json:"excluded"`
+	BySource         map[string]int `json:"bySource"`
+	ByLanguage       map[string]int `json:"byLanguage"`
+	ByYear           map[string]int `json:"byYear"`
+	LastUpdated      time.Time      `json:"lastUpdated"`
+	YouTubeQuotaUsed int64          `json:"youTubeQuotaUsed"`
+}
+
+func computeStats(c *videoCache) cacheStats {
+	stats := cacheStats{
+		BySource:         make(map[string]int),
+		ByLanguage:       make(map[string]int),
+		ByYear:           make(map[string]int),
+		LastUpdated:      c.LastUpdated,
+		YouTubeQuotaUsed: c.YouTubeQuotaUsed,
+	}
+	talks := make(map[string]bool)
+	for _, video := range c.Videos {
+		switch {
+		case !video.RemovedAt.IsZero():
+			stats.Removed++
+			continue
+		case video.Excluded != "":
+			stats.Excluded++
+			continue
+		}
+
+		stats.Videos++
+		stats.BySource[video.Source]++
+		stats.ByLanguage[video.Language]++
+		stats.ByYear[strconv.Itoa(video.PublishYear)]++
+		if video.TalkID != "" {
+			talks[video.TalkID] = true
+		} else {
+			talks[video.VideoID] = true
+		}
+	}
+	stats.Talks = len(talks)
+	return stats
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+)
+
+// devanagariVowels are the Devanagari vowels written on their own, and
+// devanagariMatras the vowel signs written after a consonant, in Hunterian
+// style Latin.
+var (
+	devanagariVowels = map[rune]string{
+		'अ': "a", 'आ': "aa", 'इ': "i", 'ई': "ee", 'उ': "u", 'ऊ': "oo", 'ऋ': "ri",
+		'ए': "e", 'ऐ': "ai", 'ओ': "o", 'औ': "au", 'ऑ': "o",
+	}
+	devanagariMatras = map[rune]string{
+		'ा': "aa", 'ि': "i", 'ी': "ee", 'ु': "u", 'ू': "oo", 'ृ': "ri",
+		'े': "e", 'ै': "ai", 'ो': "o", 'ौ': "au", 'ॉ': "o",
+	}
+	devanagariConsonants = map[rune]string{
+		'क': "k", 'ख': "kh", 'ग': "g", 'घ': "gh", 'ङ': "n",
+		'च': "ch", 'छ': "chh", 'ज': "j", 'झ': "jh", 'ञ': "n",
+		'ट': "t", 'ठ': "th", 'ड': "d", 'ढ': "dh", 'ण': "n",
+		'त': "t", 'थ': "th", 'द': "d", 'ध': "dh", 'न': "n",
+		'प': "p", 'फ': "ph", 'ब': "b", 'भ': "bh", 'म': "m",
+		'य': "y", 'र': "r", 'ल': "l", 'ळ': "l", 'व': "v",
+		'श': "sh", 'ष': "sh", 'स': "s", 'ह': "h",
+	}
+)
+
+const (
+	virama   = '्'
+	anusvara = 'ं'
+	visarga  = 'ः'
+)
+
+// transliterate writes a folded Devanagari word in Latin the way it is
+// usually typed, so शांति is shaanti and प्रेम is prem. The vowel a that
+// consonants carry is dropped at the end of words of more than one syllable,
+// as it is not spoken there. Anything not Devanagari is kept as it is.
+func transliterate(word string) string {
+	var b strings.Builder
+	runes := []rune(word)
+	syllables := 0
+	inherentA := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		inherentA = false
+		switch {
+		case devanagariConsonants[r] != "":
+			b.WriteString(devanagariConsonants[r])
+			syllables++
+			if i+1 < len(runes) && runes[i+1] == virama {
+				syllables--
+				i++
+			} else if i+1 < len(runes) && devanagariMatras[runes[i+1]] != "" {
+				b.WriteString(devanagariMatras[runes[i+1]])
+				i++
+			} else {
+				b.WriteString("a")
+				inherentA = true
+			}
+		case devanagariVowels[r] != "":
+			b.WriteString(devanagariVowels[r])
+			syllables++
+		case r == anusvara:
+			b.WriteString("n")
+		case r == visarga:
+			b.WriteString("h")
+		case r >= '०' && r <= '९':
+			b.WriteRune('0' + r - '०')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	latin := b.String()
+	if inherentA && syllables > 1 {
+		latin = strings.TrimSuffix(latin, "a")
+	}
+	return latin
+}
+
+// phoneticReplacer folds the spellings of the same sounds in romanised Hindi,
+// such as ee and i, or w and v. The longest spellings come first.
+var phoneticReplacer = strings.NewReplacer(
+	"aa", "a", "ee", "i", "ii", "i", "oo", "u", "uu", "u",
+	"ph", "f", "sh", "s", "ch", "c", "ck", "k",
+	"w", "v", "z", "j", "q", "k",
+)
+
+// phoneticKey returns a key that the usual spelling variants of a romanised
+// Hindi word share, and that a Devanagari word shares with them, so shanti,
+// shaanti and शांति all have the key santi. Aspiration is dropped, as is a
+// final a, doubled letters are written once. Keys are only compared across
+// scripts, see acrossScripts.
+func phoneticKey(word string) string {
+	if containsHindi(word) {
+		word = transliterate(word)
+	}
+	word = phoneticReplacer.Replace(word)
+
+	var b strings.Builder
+	var last rune
+	for _, r := range word {
+		if r == 'h' && last != 0 && !strings.ContainsRune("aeiou", last) {
+			continue
+		}
+		if r == last {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+
+	key := b.String()
+	if len(key) > 3 {
+		key = strings.TrimSuffix(key, "a")
+	}
+	return key
+}
+
+// acrossScripts reports whether one word is Devanagari and the other is not,
+// the only case where words are matched by their phonetic key. Two Latin words
+// are compared as spelled, as English words share keys with unrelated ones,
+// such as the and te.
+func acrossScripts(a, b string) bool {
+	return containsHindi(a) != containsHindi(b)
+}
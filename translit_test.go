@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransliterate(t *testing.T) {
+	for devanagari, latin := range map[string]string{
+		"शांति": "shaanti",
+		"आनंद":  "aanand",
+		"प्रेम": "prem",
+		"रावत":  "raavat",
+		"से":    "se",
+		"ध्यान": "dhyaan",
+		"२०२५":  "2025",
+	} {
+		assert.Equal(t, latin, transliterate(foldText(devanagari)), devanagari)
+	}
+}
+
+func TestPhoneticKey(t *testing.T) {
+	for _, words := range [][]string{
+		{"shanti", "shaanti", "शांति"},
+		{"anand", "anandh", "ananda", "आनंद"},
+		{"rawat", "raawat", "रावत"},
+		{"dhyan", "dhyaan", "ध्यान"},
+	} {
+		for _, word := range words[1:] {
+			assert.Equal(t, phoneticKey(words[0]), phoneticKey(foldText(word)), word)
+		}
+	}
+	assert.NotEqual(t, phoneticKey("peace"), phoneticKey("piece"))
+
+	node, err := parseQuery("shanti")
+	assert.NoError(t, err)
+	assert.True(t, node.match(newSearchDoc(videoMeta{Name: "Prem Rawat से जानें शांति के बारे में"})))
+
+	// Latin words only match as spelled, whatever their keys.
+	assert.Equal(t, phoneticKey("the"), phoneticKey("te"))
+	for query, name := range map[string]string{"the": "Te Deum", "dhyaan": "Dhyan"} {
+		node, err := parseQuery(query)
+		assert.NoError(t, err)
+		assert.False(t, node.match(newSearchDoc(videoMeta{Name: name})), query)
+	}
+}
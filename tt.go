@@ -16,22 +16,39 @@ const (
 	ttVideoURL       = "https://www.timelesstoday.tv/%v/home/product/%v"
 )
 
-var (
-	httpClient = &http.Client{Timeout: 10 * time.Second}
-)
-
+// getTTContent returns the videos of every language it could fetch, along
+// with a *partialError naming the languages that failed. Languages are
+// fetched concurrently but their videos are returned in a fixed order, a talk
+// listed under several languages once.
+//
+// TT does not say why it lists a talk under a language other than the one it
+// was given in, which may be subtitles, a dub or only a translated page, so
+// AudioLanguages and SubtitleLanguages are left to the overrides file.
 func getTTContent() ([]videoMeta, error) {
-	var videoList []videoMeta
+	langs := languages
+	langVideos := make([][]videoMeta, len(langs))
+	langErrs := make([]error, len(langs))
+	forEach(len(langs), func(i int) {
+		langVideos[i], langErrs[i] = getContentForLang(langs[i])
+	})
 
-	for _, lang := range []string{hindiLang, englishLang} {
-		videos, err := getContentForLang(lang)
-		if err != nil {
-			return nil, err
+	var videoList []videoMeta
+	seen := make(map[string]bool)
+	failed := &partialError{}
+	for i, lang := range langs {
+		if langErrs[i] != nil {
+			failed.add(lang, langErrs[i])
+			continue
+		}
+		for _, video := range langVideos[i] {
+			if !seen[video.VideoID] {
+				seen[video.VideoID] = true
+				videoList = append(videoList, video)
+			}
 		}
-		videoList = append(videoList, videos...)
 	}
 
-	return videoList, nil
+	return videoList, failed.orNil()
 }
 
 func getContentForLang(lang string) ([]videoMeta, error) {
@@ -43,7 +60,7 @@ func getContentForLang(lang string) ([]videoMeta, error) {
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:144.0) Gecko/20100101 Firefox/144.0")
 
-	resp, err := httpClient.Do(req)
+	resp, err := doRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("error getting video list for lang [%v]: %w", lang, err)
 	}
@@ -101,14 +118,3 @@ func getContentForLang(lang string) ([]videoMeta, error) {
 func getClickURL(mediaUUID, lang string) string {
 	return fmt.Sprintf(ttVideoURL, getLangTwoLetterCode(lang), mediaUUID)
 }
-
-func getLangTwoLetterCode(lang string) string {
-	switch lang {
-	case "en-US":
-		return "en"
-	case "hi-IN":
-		return "hi"
-	default:
-		return "en"
-	}
-}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,39 +22,134 @@ const (
 
 	youTubeVideoURL   = "https://www.youtube.com/watch?v=%v"
 	baseYouTubeAPIURL = "https://www.googleapis.com/youtube/v3"
-	playlistURL       = baseYouTubeAPIURL + "/channels?part=contentDetails&forHandle=%v&key=%v"
-	videoListURL      = baseYouTubeAPIURL + "/playlistItems?part=snippet&maxResults=50&playlistId=%v&key=%v&pageToken=%v"
-	videoMetaURL      = baseYouTubeAPIURL + "/videos?part=snippet,contentDetails&id=%v&key=%v"
+
+	// maxIDsPerMetaRequest is the most video IDs the videos endpoint accepts at once.
+	maxIDsPerMetaRequest = 50
 )
 
 var (
 	allYtHandles = []string{wopgHandle, prHandle, rvkHandle, ttHandle}
+
+	// The API URLs are variables so that tests can point them at a stub
+	// server.
+	playlistURL  = baseYouTubeAPIURL + "/channels?part=contentDetails&forHandle=%v&key=%v"
+	channelIDURL = baseYouTubeAPIURL + "/channels?part=contentDetails&id=%v&key=%v"
+	videoListURL = baseYouTubeAPIURL + "/playlistItems?part=snippet&maxResults=50&playlistId=%v&key=%v&pageToken=%v"
+	videoMetaURL = baseYouTubeAPIURL + "/videos?part=snippet,contentDetails&id=%v&key=%v"
+
+	// youTubeChannels are the channels and playlists videos are fetched from,
+	// the ones in allYtHandles followed by any configured at runtime.
+	youTubeChannels = handleChannels(allYtHandles)
+
+	// youTubeFullSyncInterval is how often all pages of every uploads playlist
+	// are listed, in between only the pages up to the first one without new
+	// videos are. Zero always lists every page.
+	youTubeFullSyncInterval = 7 * 24 * time.Hour
+
+	// revalidateAfter is the age after which the metadata of a cached video
+	// is looked up again, zero never looks it up again because of its age.
+	revalidateAfter time.Duration
+	// revalidateChanged looks up the metadata of cached videos again when
+	// their title, description or thumbnail changed on YouTube.
+	revalidateChanged = true
 )
 
-func getYouTubeContent() ([]videoMeta, error) {
+func handleChannels(handles []string) []youTubeChannel {
+	channels := make([]youTubeChannel, 0, len(handles))
+	for _, handle := range handles {
+		channels = append(channels, youTubeChannel{Handle: handle})
+	}
+	return channels
+}
+
+// getYouTubeContent returns the videos of every channel it could fetch, along
+// with a *partialError naming the channels that failed or that were cut short
+// by the quota budget. Channels are fetched concurrently but their videos are
+// returned in the order of youTubeChannels, a video listed by several of them
+// is returned once with all their tags. An incremental fetch stops listing the
+// uploads playlist of a channel at the first page whose videos are all cached
+// already.
+func getYouTubeContent(incremental bool) ([]videoMeta, error) {
+	channelVideos := make([][]videoMeta, len(youTubeChannels))
+	channelErrs := make([]error, len(youTubeChannels))
+	forEach(len(youTubeChannels), func(i int) {
+		channelVideos[i], channelErrs[i] = getChannelContent(youTubeChannels[i], incremental)
+	})
+
 	var videos []videoMeta
-	for _, handle := range allYtHandles {
-		log.Printf("getting videos from handle: [%v]\n", handle)
+	index := make(map[string]int)
+	failed := &partialError{}
+	for i, ch := range youTubeChannels {
+		if channelErrs[i] != nil {
+			failed.add(ch.String(), channelErrs[i])
+		}
 
-		playlistID, err := getPlaylistID(handle)
-		if err != nil {
-			return nil, fmt.Errorf("error getting playlist ID for [%v]: %v", handle, err)
+		for _, video := range channelVideos[i] {
+			j, seen := index[video.VideoID]
+			if !seen {
+				index[video.VideoID] = len(videos)
+				videos = append(videos, video)
+				continue
+			}
+			if videos[j].Channel == "" {
+				videos[j].Channel = video.Channel
+			}
+			for _, tag := range video.Tags {
+				if !slices.Contains(videos[j].Tags, tag) {
+					videos[j].Tags = append(videos[j].Tags, tag)
+				}
+			}
 		}
+	}
+	youTubeQuota.logUsage("fetching all channels")
 
-		playlistVideos, err := getVideosFromPlaylist(playlistID)
-		if err != nil {
-			return nil, fmt.Errorf("error getting videos from playlist [%v]: %v", playlistID, err)
+	return videos, failed.orNil()
+}
+
+func getChannelContent(ch youTubeChannel, incremental bool) ([]videoMeta, error) {
+	log.Printf("getting videos from: [%v]\n", ch)
+
+	playlistID := ch.Playlist
+	if playlistID == "" {
+		var err error
+		if playlistID, err = getPlaylistID(ch); err != nil {
+			return nil, fmt.Errorf("error getting playlist ID: %w", err)
 		}
+	}
 
-		videos = append(videos, playlistVideos...)
+	// Only uploads playlists are listed newest first, others can get videos
+	// added anywhere and are always listed in full.
+	videos, err := getVideosFromPlaylist(playlistID, incremental && ch.Playlist == "", ch.Language)
+	if err != nil {
+		err = fmt.Errorf("error getting videos from playlist [%v]: %w", playlistID, err)
 	}
+	for i := range videos {
+		if ch.Playlist == "" {
+			videos[i].Channel = cmp.Or(ch.Handle, ch.ChannelID)
+		}
+		videos[i].Tags = nil
+		if ch.Tag != "" {
+			videos[i].Tags = []string{ch.Tag}
+		}
+	}
+	youTubeQuota.logUsage(fmt.Sprintf("fetching [%v]", ch))
 
-	return videos, nil
+	return videos, err
 }
 
-func getPlaylistID(handle string) (string, error) {
+// getPlaylistID returns the uploads playlist of a channel given by handle or ID.
+func getPlaylistID(ch youTubeChannel) (string, error) {
+	if err := youTubeQuota.spend("channels"); err != nil {
+		return "", err
+	}
+
+	handle := ch.String()
 	youTubeAPIKey := os.Getenv("YOUTUBE_API_KEY")
-	resp, err := httpClient.Get(fmt.Sprintf(playlistURL, handle, youTubeAPIKey))
+	url := fmt.Sprintf(playlistURL, ch.Handle, youTubeAPIKey)
+	if ch.Handle == "" {
+		url = fmt.Sprintf(channelIDURL, ch.ChannelID, youTubeAPIKey)
+	}
+	resp, err := httpGet(url)
 	if err != nil {
 		return "", fmt.Errorf("error getting playlist ID for [%v]: %v", handle, err)
 	}
@@ -82,120 +181,320 @@ func getPlaylistID(handle string) (string, error) {
 	return respstruct.Items[0].ContentDetails.RelatedPlaylists.Uploads, nil
 }
 
-func getVideosFromPlaylist(playlistID string) ([]videoMeta, error) {
-	youTubeAPIKey := os.Getenv("YOUTUBE_API_KEY")
+// playlistItem is a video as listed by the playlistItems endpoint.
+type playlistItem struct {
+	Snippet struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		PublishedAt string `json:"publishedAt"`
+		Thumbnails  struct {
+			Medium struct {
+				URL string `json:"url"`
+			} `json:"medium"`
+		} `json:"thumbnails"`
+		ResourceID struct {
+			VideoID string `json:"videoId"`
+		} `json:"resourceId"`
+	} `json:"snippet"`
+}
 
-	var videos []videoMeta
+// getVideosFromPlaylist lists the pages of the playlist and then looks up the
+// metadata of each page's uncached videos concurrently. Uploads playlists are
+// newest first, so an incremental listing stops at the first page whose
+// videos are all cached. When the quota budget runs out, the videos known so
+// far are returned with errQuotaExhausted. Videos YouTube knows no supported
+// language for get defaultLang, or are skipped without one.
+func getVideosFromPlaylist(playlistID string, incremental bool, defaultLang string) ([]videoMeta, error) {
+	var pages [][]playlistItem
+	var quotaErr error
 	nextPageToken := ""
-	pageNo := 0
 	for {
-		pageNo++
 		log.Printf("getting page [%v] of videos from playlist [%v], nextPageToken: [%v]\n",
-			pageNo, playlistID, nextPageToken)
+			len(pages)+1, playlistID, nextPageToken)
 
-		resp, err := httpClient.Get(fmt.Sprintf(videoListURL, playlistID, youTubeAPIKey, nextPageToken))
+		items, pageToken, err := getPlaylistPage(playlistID, nextPageToken)
+		if errors.Is(err, errQuotaExhausted) {
+			log.Printf("stopped listing playlist [%v] after [%v] pages: %v\n", playlistID, len(pages), err)
+			quotaErr = err
+			break
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error getting videos from playlist [%v]: %v", playlistID, err)
+			return nil, err
+		}
+		if len(items) == 0 {
+			break
 		}
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("error getting videos from playlist [%v]: %v", playlistID, resp.Status)
+		pages = append(pages, items)
+
+		if incremental && allCached(items) {
+			log.Printf("page [%v] of playlist [%v] has no new videos, stopping\n", len(pages), playlistID)
+			break
 		}
-		defer func() {
-			if err := resp.Body.Close(); err != nil {
-				log.Printf("error while closing response body for playlist %s: %v", playlistID, err)
-			}
-		}()
-
-		var respstruct struct {
-			NextPageToken string `json:"nextPageToken"`
-			Items         []struct {
-				Snippet struct {
-					Title       string `json:"title"`
-					Description string `json:"description"`
-					PublishedAt string `json:"publishedAt"`
-					Thumbnails  struct {
-						Medium struct {
-							URL string `json:"url"`
-						} `json:"medium"`
-					} `json:"thumbnails"`
-					ResourceID struct {
-						VideoID string `json:"videoId"`
-					} `json:"resourceId"`
-				} `json:"snippet"`
-			} `json:"items"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&respstruct); err != nil {
-			return nil, fmt.Errorf("error decoding response in getting videos from playlist [%v]: %v", playlistID, err)
-		}
-		if len(respstruct.Items) == 0 {
+
+		nextPageToken = pageToken
+		if nextPageToken == "" {
 			break
 		}
+	}
 
-		for _, item := range respstruct.Items {
-			video, ok := cache.get(item.Snippet.ResourceID.VideoID)
-			if ok {
-				videos = append(videos, video)
-				continue
-			}
+	pageVideos := make([][]videoMeta, len(pages))
+	pageErrs := make([]error, len(pages))
+	forEach(len(pages), func(i int) {
+		pageVideos[i], pageErrs[i] = getVideosForPage(pages[i], defaultLang)
+	})
 
-			publishTs, err := time.Parse("2006-01-02T15:04:05Z", item.Snippet.PublishedAt)
-			if err != nil {
-				return nil, fmt.Errorf("error parsing publish date for video [%+v]: %w", item, err)
-			}
+	var videos []videoMeta
+	for i := range pages {
+		switch {
+		case errors.Is(pageErrs[i], errQuotaExhausted):
+			quotaErr = pageErrs[i]
+		case pageErrs[i] != nil:
+			return nil, pageErrs[i]
+		}
+		videos = append(videos, pageVideos[i]...)
+	}
 
-			audioLang, duration, err := getMetaForYouTubeVideo(item.Snippet.ResourceID.VideoID)
-			if err != nil {
-				return nil, err
-			}
+	return videos, quotaErr
+}
 
-			if duration == 0 || (audioLang != "hi-IN" && audioLang != "en-US") {
-				continue
-			}
+func getPlaylistPage(playlistID, pageToken string) ([]playlistItem, string, error) {
+	if err := youTubeQuota.spend("playlistItems"); err != nil {
+		return nil, "", err
+	}
 
-			videos = append(videos, videoMeta{
-				VideoID:       item.Snippet.ResourceID.VideoID,
-				Name:          item.Snippet.Title,
-				Description:   item.Snippet.Description,
-				VideoDuration: duration,
-				Language:      audioLang,
-				ClickURL:      fmt.Sprintf(youTubeVideoURL, item.Snippet.ResourceID.VideoID),
-				PublishYear:   publishTs.Year(),
-				PublishMonth:  publishTs.Month(),
-				PublishDay:    publishTs.Day(),
-				ThumbnailURL:  item.Snippet.Thumbnails.Medium.URL,
-				AudioOnly:     false,
-			})
-		}
-
-		nextPageToken = respstruct.NextPageToken
-		if nextPageToken == "" {
-			break
+	youTubeAPIKey := os.Getenv("YOUTUBE_API_KEY")
+	resp, err := httpGet(fmt.Sprintf(videoListURL, playlistID, youTubeAPIKey, pageToken))
+	if err != nil {
+		return nil, "", fmt.Errorf("error getting videos from playlist [%v]: %v", playlistID, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("error getting videos from playlist [%v]: %v", playlistID, resp.Status)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("error while closing response body for playlist %s: %v", playlistID, err)
 		}
+	}()
+
+	var respstruct struct {
+		NextPageToken string         `json:"nextPageToken"`
+		Items         []playlistItem `json:"items"`
 	}
+	if err := json.NewDecoder(resp.Body).Decode(&respstruct); err != nil {
+		return nil, "", fmt.Errorf("error decoding response in getting videos from playlist [%v]: %v", playlistID, err)
+	}
+
+	return respstruct.Items, respstruct.NextPageToken, nil
+}
+
+// skippedMu guards cache.SkippedYouTubeIDs while pages are fetched concurrently.
+var skippedMu sync.Mutex
+
+// allCached reports whether every video of a page is cached or was skipped
+// before.
+func allCached(items []playlistItem) bool {
+	skippedMu.Lock()
+	defer skippedMu.Unlock()
+
+	for _, item := range items {
+		id := item.Snippet.ResourceID.VideoID
+		if _, ok := cache.get(id); !ok && !cache.SkippedYouTubeIDs[id] {
+			return false
+		}
+	}
+	return true
+}
+
+func markSkipped(videoID string, skipped bool) {
+	skippedMu.Lock()
+	defer skippedMu.Unlock()
+
+	if !skipped {
+		delete(cache.SkippedYouTubeIDs, videoID)
+		return
+	}
+	if cache.SkippedYouTubeIDs == nil {
+		cache.SkippedYouTubeIDs = make(map[string]bool)
+	}
+	cache.SkippedYouTubeIDs[videoID] = true
+}
+
+// getVideosForPage returns the videos of a playlist page, looking up the
+// metadata of the ones not in the cache or due for revalidation with a single
+// batched request. When the quota budget runs out, uncached videos are left
+// out and errQuotaExhausted is returned along with the cached ones.
+func getVideosForPage(items []playlistItem, defaultLang string) ([]videoMeta, error) {
+	var lookupIDs []string
+	for _, item := range items {
+		video, ok := cache.get(item.Snippet.ResourceID.VideoID)
+		if !ok || needsRevalidation(video, item) {
+			lookupIDs = append(lookupIDs, item.Snippet.ResourceID.VideoID)
+		}
+	}
+
+	metas, metaErr := getMetaForYouTubeVideos(lookupIDs)
+	if metaErr != nil && !errors.Is(metaErr, errQuotaExhausted) {
+		return nil, metaErr
+	}
+
+	var videos []videoMeta
+	for _, item := range items {
+		meta, ok := metas[item.Snippet.ResourceID.VideoID]
+		if video, cached := cache.get(item.Snippet.ResourceID.VideoID); cached && !ok {
+			videos = append(videos, video)
+			continue
+		}
+		if !ok && metaErr != nil {
+			continue
+		}
+		if !ok {
+			log.Printf("no meta found for video [%v], skipping it\n", item.Snippet.ResourceID.VideoID)
+			continue
+		}
 
-	return videos, nil
+		publishTs, err := time.Parse("2006-01-02T15:04:05Z", item.Snippet.PublishedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing publish date for video [%+v]: %w", item, err)
+		}
+
+		if !supportedLang(meta.audioLang) && defaultLang != "" {
+			meta.audioLang = defaultLang
+		}
+		if meta.duration == 0 || !supportedLang(meta.audioLang) {
+			markSkipped(item.Snippet.ResourceID.VideoID, true)
+			continue
+		}
+		markSkipped(item.Snippet.ResourceID.VideoID, false)
+
+		videos = append(videos, videoMeta{
+			VideoID:       item.Snippet.ResourceID.VideoID,
+			Name:          item.Snippet.Title,
+			Description:   item.Snippet.Description,
+			VideoDuration: meta.duration,
+			Language:      meta.audioLang,
+			ClickURL:      fmt.Sprintf(youTubeVideoURL, item.Snippet.ResourceID.VideoID),
+			PublishYear:   publishTs.Year(),
+			PublishMonth:  publishTs.Month(),
+			PublishDay:    publishTs.Day(),
+			ThumbnailURL:  item.Snippet.Thumbnails.Medium.URL,
+			AudioOnly:     false,
+			MetaFetchedAt: time.Now(),
+		})
+	}
+
+	return videos, metaErr
 }
 
-func getMetaForYouTubeVideo(videoID string) (string, time.Duration, error) {
+// needsRevalidation reports whether a cached video's metadata should be looked
+// up again, either because it is older than revalidateAfter or because its
+// snippet in the playlist listing no longer matches the cached one. Fields set
+// by an override patch are not compared.
+func needsRevalidation(video videoMeta, item playlistItem) bool {
+	if revalidateAfter > 0 && time.Since(video.MetaFetchedAt) > revalidateAfter {
+		return true
+	}
+
+	// The cached video has its patches applied, so the listed one is
+	// patched too before comparing.
+	listed := videoMeta{
+		Name:         item.Snippet.Title,
+		Description:  item.Snippet.Description,
+		ThumbnailURL: item.Snippet.Thumbnails.Medium.URL,
+	}
+	if patch := overrides.patchFor(video.VideoID); patch != nil {
+		patch.applyTo(&listed)
+	}
+	return revalidateChanged && (video.Name != listed.Name ||
+		video.Description != listed.Description ||
+		video.ThumbnailURL != listed.ThumbnailURL)
+}
+
+// youTubeVideoMeta is a video's metadata as returned by the videos endpoint.
+// Playlist pages only need the language and duration, which the playlist
+// listing does not include, while videos added by overrides use all of it.
+type youTubeVideoMeta struct {
+	audioLang   string
+	duration    time.Duration
+	title       string
+	description string
+	publishedAt string
+	thumbnail   string
+}
+
+// toVideo builds a cached video from the metadata alone.
+func (m youTubeVideoMeta) toVideo(videoID string) (videoMeta, error) {
+	publishTs, err := time.Parse("2006-01-02T15:04:05Z", m.publishedAt)
+	if err != nil {
+		return videoMeta{}, fmt.Errorf("error parsing publish date [%v] for video [%v]: %w", m.publishedAt, videoID, err)
+	}
+
+	return videoMeta{
+		VideoID:       videoID,
+		Name:          m.title,
+		Description:   m.description,
+		VideoDuration: m.duration,
+		Language:      m.audioLang,
+		ClickURL:      fmt.Sprintf(youTubeVideoURL, videoID),
+		PublishYear:   publishTs.Year(),
+		PublishMonth:  publishTs.Month(),
+		PublishDay:    publishTs.Day(),
+		ThumbnailURL:  m.thumbnail,
+		MetaFetchedAt: time.Now(),
+	}, nil
+}
+
+// getMetaForYouTubeVideos looks up the metadata of the given videos, batching
+// up to maxIDsPerMetaRequest IDs in each request. Videos that YouTube does not
+// return, such as private or deleted ones, are missing from the result. When
+// the quota budget runs out, the metadata found so far is returned with
+// errQuotaExhausted.
+func getMetaForYouTubeVideos(videoIDs []string) (map[string]youTubeVideoMeta, error) {
+	metas := make(map[string]youTubeVideoMeta, len(videoIDs))
+	for start := 0; start < len(videoIDs); start += maxIDsPerMetaRequest {
+		if err := youTubeQuota.spend("videos"); err != nil {
+			return metas, err
+		}
+
+		batch := videoIDs[start:min(start+maxIDsPerMetaRequest, len(videoIDs))]
+		if err := getMetaForYouTubeBatch(batch, metas); err != nil {
+			return nil, err
+		}
+	}
+
+	return metas, nil
+}
+
+func getMetaForYouTubeBatch(videoIDs []string, metas map[string]youTubeVideoMeta) error {
+	ids := strings.Join(videoIDs, ",")
+	log.Printf("getting meta for [%v] videos\n", len(videoIDs))
+
 	youTubeAPIKey := os.Getenv("YOUTUBE_API_KEY")
-	resp, err := httpClient.Get(fmt.Sprintf(videoMetaURL, videoID, youTubeAPIKey))
+	resp, err := httpGet(fmt.Sprintf(videoMetaURL, ids, youTubeAPIKey))
 	if err != nil {
-		return "", 0, fmt.Errorf("error getting meta for video [%v]: %v", videoID, err)
+		return fmt.Errorf("error getting meta for videos [%v]: %v", ids, err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return "", 0, fmt.Errorf("error getting meta for video [%v]: %v", videoID, resp.Status)
+		return fmt.Errorf("error getting meta for videos [%v]: %v", ids, resp.Status)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("error while closing response body for video %s: %v", videoID, err)
+			log.Printf("error while closing response body for videos %s: %v", ids, err)
 		}
 	}()
 
 	var respstruct struct {
 		Items []struct {
+			ID      string `json:"id"`
 			Snippet struct {
-				Title     string `json:"title"`
-				AudioLang string `json:"defaultAudioLanguage"`
+				Title       string `json:"title"`
+				Description string `json:"description"`
+				PublishedAt string `json:"publishedAt"`
+				AudioLang   string `json:"defaultAudioLanguage"`
+				Thumbnails  struct {
+					Medium struct {
+						URL string `json:"url"`
+					} `json:"medium"`
+				} `json:"thumbnails"`
 			} `json:"snippet"`
 			ContentDetails struct {
 				Duration string `json:"duration"`
@@ -203,19 +502,26 @@ func getMetaForYouTubeVideo(videoID string) (string, time.Duration, error) {
 		} `json:"items"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&respstruct); err != nil {
-		return "", 0, fmt.Errorf("error decoding response while getting meta for video [%v]: %v", videoID, err)
-	}
-	if len(respstruct.Items) == 0 {
-		return "", 0, fmt.Errorf("no meta found for video [%v]", videoID)
+		return fmt.Errorf("error decoding response while getting meta for videos [%v]: %v", ids, err)
 	}
 
-	durationStr := respstruct.Items[0].ContentDetails.Duration
-	duration, err := parseDuration(durationStr)
-	if err != nil {
-		return "", 0, fmt.Errorf("error parsing duration [%v] for video [%v]: %v", durationStr, videoID, err)
+	for _, item := range respstruct.Items {
+		duration, err := parseDuration(item.ContentDetails.Duration)
+		if err != nil {
+			return fmt.Errorf("error parsing duration [%v] for video [%v]: %v", item.ContentDetails.Duration, item.ID, err)
+		}
+
+		metas[item.ID] = youTubeVideoMeta{
+			audioLang:   langTT(item.Snippet.AudioLang, item.Snippet.Title),
+			duration:    duration,
+			title:       item.Snippet.Title,
+			description: item.Snippet.Description,
+			publishedAt: item.Snippet.PublishedAt,
+			thumbnail:   item.Snippet.Thumbnails.Medium.URL,
+		}
 	}
 
-	return langTT(respstruct.Items[0].Snippet.AudioLang, respstruct.Items[0].Snippet.Title), duration, nil
+	return nil
 }
 
 func parseDuration(duration string) (time.Duration, error) {
@@ -226,22 +532,16 @@ func parseDuration(duration string) (time.Duration, error) {
 	return time.ParseDuration(strings.ToLower(duration[2:]))
 }
 
+// langTT returns the configured language a YouTube video is in, from the
+// audio language YouTube reports and the script of its title. Titles in
+// Devanagari are taken to be Hindi, unless YouTube reports another language
+// written in it that is configured too.
 func langTT(lang, title string) string {
-	titleHasHindi := containsHindi(title)
-	if titleHasHindi {
-		return "hi-IN"
-	}
-
-	switch lang {
-	case "hi", "hi-IN":
-		return "hi-IN"
-
-	case "en-GB", "en-US", "en":
-		return "en-US"
-
-	default:
-		return lang
+	lang = normalizeLang(lang)
+	if containsHindi(title) && !(slices.Contains(devanagariLangs, getLangTwoLetterCode(lang)) && supportedLang(lang)) {
+		return hindiLang
 	}
+	return lang
 }
 
 func containsHindi(title string) bool {
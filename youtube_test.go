@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubYouTube serves an uploads playlist of three pages, v1 v2, v3 v4 and v5,
+// and the metadata of every video, counting the requests made to each
+// endpoint.
+func stubYouTube(t *testing.T) map[string]int {
+	pages := map[string]struct {
+		ids  []string
+		next string
+	}{
+		"":   {[]string{"v1", "v2"}, "p2"},
+		"p2": {[]string{"v3", "v4"}, "p3"},
+		"p3": {[]string{"v5"}, ""},
+	}
+
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		query := r.URL.Query()
+		var resp any
+		switch r.URL.Path {
+		case "/channels":
+			resp = map[string]any{"items": []any{map[string]any{
+				"contentDetails": map[string]any{"relatedPlaylists": map[string]string{"uploads": "UU1"}},
+			}}}
+		case "/playlistItems":
+			page := pages[query.Get("pageToken")]
+			var items []any
+			for _, id := range page.ids {
+				items = append(items, map[string]any{"snippet": map[string]any{
+					"title": "Talk " + id, "publishedAt": "2025-11-21T10:00:00Z",
+					"resourceId": map[string]string{"videoId": id},
+				}})
+			}
+			resp = map[string]any{"nextPageToken": page.next, "items": items}
+		case "/videos":
+			var items []any
+			for _, id := range strings.Split(query.Get("id"), ",") {
+				items = append(items, map[string]any{
+					"id":             id,
+					"snippet":        map[string]any{"title": "Talk " + id, "defaultAudioLanguage": "hi"},
+					"contentDetails": map[string]string{"duration": "PT40M"},
+				})
+			}
+			resp = map[string]any{"items": items}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	t.Cleanup(server.Close)
+
+	savedURLs := []string{playlistURL, channelIDURL, videoListURL, videoMetaURL}
+	savedCache, savedOverrides := cache, overrides
+	t.Cleanup(func() {
+		playlistURL, channelIDURL, videoListURL, videoMetaURL = savedURLs[0], savedURLs[1], savedURLs[2], savedURLs[3]
+		cache, overrides = savedCache, savedOverrides
+	})
+	playlistURL = server.URL + "/channels?forHandle=%v&key=%v"
+	channelIDURL = server.URL + "/channels?id=%v&key=%v"
+	videoListURL = server.URL + "/playlistItems?playlistId=%v&key=%v&pageToken=%v"
+	videoMetaURL = server.URL + "/videos?id=%v&key=%v"
+	cache = videoCache{Videos: make(map[string]videoMeta)}
+	overrides = overrideSet{}
+	return requests
+}
+
+func videoIDs(videos []videoMeta) []string {
+	ids := make([]string, 0, len(videos))
+	for _, video := range videos {
+		ids = append(ids, video.VideoID)
+	}
+	return ids
+}
+
+func TestIncrementalPlaylistListing(t *testing.T) {
+	requests := stubYouTube(t)
+	// The second page is cached, the first has new videos.
+	for _, id := range []string{"v3", "v4"} {
+		cache.set(videoMeta{VideoID: id, Name: "Talk " + id, Source: "youtube", MetaFetchedAt: time.Now()})
+	}
+
+	videos, err := getVideosFromPlaylist("UU1", true, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1", "v2", "v3", "v4"}, videoIDs(videos))
+	assert.Equal(t, 2, requests["/playlistItems"], "stops after the first page that is all cached")
+	assert.Equal(t, 1, requests["/videos"], "only the new videos are looked up")
+	assert.Equal(t, hindiLang, videos[0].Language)
+
+	clear(requests)
+	videos, err = getVideosFromPlaylist("UU1", false, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1", "v2", "v3", "v4", "v5"}, videoIDs(videos))
+	assert.Equal(t, 3, requests["/playlistItems"], "a full sync lists every page")
+}
+
+func TestYouTubeFullSyncSwitch(t *testing.T) {
+	savedCache, savedInterval := cache, youTubeFullSyncInterval
+	t.Cleanup(func() { cache, youTubeFullSyncInterval = savedCache, savedInterval })
+	youTubeFullSyncInterval = 7 * 24 * time.Hour
+
+	src := youTubeSource{}
+	cache = videoCache{}
+	assert.False(t, src.incremental(), "never fully synced")
+	cache.FullSyncs = map[string]time.Time{"youtube": time.Now().Add(-time.Hour)}
+	assert.True(t, src.incremental())
+	cache.FullSyncs["youtube"] = time.Now().Add(-8 * 24 * time.Hour)
+	assert.False(t, src.incremental(), "last full sync is over a week old")
+	cache.FullSyncs["youtube"] = time.Now()
+	youTubeFullSyncInterval = 0
+	assert.False(t, src.incremental(), "zero always lists every page")
+}
+
+func TestQuotaBudget(t *testing.T) {
+	var q quota
+	q.reset(2)
+	assert.NoError(t, q.spend("playlistItems"))
+	assert.NoError(t, q.spend("videos"))
+	assert.ErrorIs(t, q.spend("videos"), errQuotaExhausted)
+	assert.Equal(t, int64(2), q.total(), "a refused call is not counted")
+
+	requests := stubYouTube(t)
+	t.Cleanup(func() { youTubeQuota.reset(0) })
+	for _, id := range []string{"v3", "v4"} {
+		cache.set(videoMeta{VideoID: id, Name: "Talk " + id, Source: "youtube", MetaFetchedAt: time.Now()})
+	}
+
+	// Two pages are listed, then neither the third page nor the metadata of
+	// the new videos on the first fits the budget.
+	youTubeQuota.reset(2)
+	videos, err := getVideosFromPlaylist("UU1", false, "")
+	assert.ErrorIs(t, err, errQuotaExhausted)
+	assert.Equal(t, []string{"v3", "v4"}, videoIDs(videos), "the cached videos found so far")
+	assert.Equal(t, 2, requests["/playlistItems"])
+	assert.Equal(t, 0, requests["/videos"])
+
+	// A refresh cut short by the budget keeps the videos it did not get to.
+	t.Chdir(t.TempDir())
+	savedChannels, savedSources := youTubeChannels, enabledSources
+	t.Cleanup(func() { youTubeChannels, enabledSources = savedChannels, savedSources })
+	youTubeChannels = []youTubeChannel{{Handle: "@h"}}
+	enabledSources = []contentSource{youTubeSource{}}
+	cache.set(videoMeta{VideoID: "older", Name: "Older talk", Source: "youtube", MetaFetchedAt: time.Now()})
+
+	youTubeQuota.reset(3)
+	var refreshErr *refreshError
+	assert.ErrorAs(t, cache.download(), &refreshErr)
+	v, _ := cache.get("older")
+	assert.True(t, v.RemovedAt.IsZero(), "not marked removed")
+	_, ok := cache.get("v1")
+	assert.False(t, ok, "no quota left to look it up")
+	assert.Equal(t, int64(3), cache.YouTubeQuotaUsed)
+	assert.NotContains(t, cache.FullSyncs, "youtube")
+}